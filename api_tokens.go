@@ -0,0 +1,211 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	scopeChirpsRead   = "chirps:read"
+	scopeChirpsWrite  = "chirps:write"
+	scopeProfileWrite = "profile:write"
+	scopeFollowsWrite = "follows:write"
+
+	apiTokenPrefix      = "chirpy_pat_"
+	maxAPITokenNameLen  = 100
+	maxAPITokensPerUser = 50
+)
+
+var knownScopes = []string{scopeChirpsRead, scopeChirpsWrite, scopeProfileWrite, scopeFollowsWrite}
+
+var errInsufficientScope = errors.New("Token does not have the required scope")
+
+// APIToken describes a personal access token. The token itself is only
+// returned once, when it is created.
+type APIToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func apiTokenFromDB(dbToken database.ApiToken) APIToken {
+	token := APIToken{
+		ID:        dbToken.ID,
+		Name:      dbToken.Name,
+		Scopes:    dbToken.Scopes,
+		CreatedAt: dbToken.CreatedAt,
+	}
+	if dbToken.LastUsedAt.Valid {
+		token.LastUsedAt = &dbToken.LastUsedAt.Time
+	}
+	if dbToken.ExpiresAt.Valid {
+		token.ExpiresAt = &dbToken.ExpiresAt.Time
+	}
+	return token
+}
+
+// authorizedUser authenticates a request made either with a bearer JWT,
+// which carries the user's full access, or with a personal access token,
+// which must have been granted scope.
+func (cfg *apiConfig) authorizedUser(r *http.Request, scope string) (uuid.UUID, error) {
+	token, err := auth.GetPersonalToken(r.Header)
+	if err != nil {
+		return cfg.authenticatedUser(r)
+	}
+	ctx := r.Context()
+	dbToken, err := cfg.database.GetActiveAPIToken(ctx, auth.HashToken(token))
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if !slices.Contains(dbToken.Scopes, scope) {
+		return uuid.UUID{}, errInsufficientScope
+	}
+	err = cfg.database.TouchAPIToken(ctx, dbToken.ID)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+	return dbToken.UserID, nil
+}
+
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInsufficientScope) {
+		respondWithError(w, 403, errInsufficientScope.Error())
+		return
+	}
+	respondWithError(w, 401, "Unauthorized")
+}
+
+func (cfg *apiConfig) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	type tokenRequest struct {
+		Name      string   `json:"name"`
+		Scopes    []string `json:"scopes"`
+		ExpiresIn string   `json:"expires_in"`
+	}
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	request := tokenRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || len(request.Name) > maxAPITokenNameLen {
+		respondWithError(w, 400, "Token name must be 1 to 100 characters")
+		return
+	}
+	if len(request.Scopes) == 0 {
+		respondWithError(w, 400, "At least one scope is required")
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(knownScopes, scope) {
+			respondWithError(w, 400, fmt.Sprintf("Unknown scope %q", scope))
+			return
+		}
+	}
+	slices.Sort(request.Scopes)
+	request.Scopes = slices.Compact(request.Scopes)
+	expiresAt := sql.NullTime{}
+	if request.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			respondWithError(w, 400, "Invalid expires_in")
+			return
+		}
+		expiresAt = sql.NullTime{Time: time.Now().UTC().Add(expiresIn), Valid: true}
+	}
+	active, err := cfg.database.CountActiveAPITokens(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to create token")
+		return
+	}
+	if active >= maxAPITokensPerUser {
+		respondWithError(w, 400, "Too many tokens, revoke one first")
+		return
+	}
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, 500, "Unable to create token")
+		return
+	}
+	plaintext := apiTokenPrefix + secret
+	dbToken, err := cfg.database.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		UserID:    userID,
+		Name:      request.Name,
+		TokenHash: auth.HashToken(plaintext),
+		Scopes:    request.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to create token")
+		return
+	}
+	token := apiTokenFromDB(dbToken)
+	token.Token = plaintext
+	respondWithJSON(w, 201, token)
+}
+
+func (cfg *apiConfig) getAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	dbTokens, err := cfg.database.GetUserAPITokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve tokens")
+		return
+	}
+	tokens := []APIToken{}
+	for _, dbToken := range dbTokens {
+		tokens = append(tokens, apiTokenFromDB(dbToken))
+	}
+	respondWithJSON(w, 200, tokens)
+}
+
+func (cfg *apiConfig) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid token ID")
+		return
+	}
+	revoked, err := cfg.database.RevokeAPIToken(r.Context(), database.RevokeAPITokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Unable to revoke token")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Token not found")
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+// errBlocked is returned for follows, replies, quotes and rechirps between
+// two users when either has blocked the other. It does not say which one did.
+var errBlocked = errors.New("Unable to interact with this user")
+
+type RelationEntry struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e RelationEntry) position() (time.Time, uuid.UUID) {
+	return e.CreatedAt, e.UserID
+}
+
+// blockedBetween reports whether either user has blocked the other.
+func (cfg *apiConfig) blockedBetween(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	return cfg.database.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{
+		UserA: userA,
+		UserB: userB,
+	})
+}
+
+// relationTarget authorizes the caller and reads the user they want to
+// block or mute from the path, writing the error response if either fails.
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := cfg.authorizedUser(r, scopeFollowsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	return userID, targetID, true
+}
+
+// blockUser hides each user's chirps from the other and removes any follows
+// between them, in both directions.
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	if targetID == userID {
+		respondWithError(w, 400, "Unable to block yourself")
+		return
+	}
+	_, err := cfg.database.GetUser(ctx, targetID)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to block user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	err = qtx.BlockUser(ctx, database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to block user")
+		return
+	}
+	err = qtx.DeleteFollowsBetween(ctx, database.DeleteFollowsBetweenParams{
+		UserA: userID,
+		UserB: targetID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to block user")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to block user")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.database.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to unblock user")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// muteUser hides the muted user's chirps from the caller's listings only;
+// the muted user is unaffected and can still follow, mention and reply.
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	if targetID == userID {
+		respondWithError(w, 400, "Unable to mute yourself")
+		return
+	}
+	_, err := cfg.database.GetUser(ctx, targetID)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	err = cfg.database.MuteUser(ctx, database.MuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to mute user")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.database.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to unmute user")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeFollowsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	rows, err := cfg.database.GetBlockedUsers(ctx, database.GetBlockedUsersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt,
+		CursorID:        page.cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve blocked users")
+		return
+	}
+	var entries []RelationEntry
+	for _, row := range rows {
+		entries = append(entries, RelationEntry{UserID: row.BlockedID, CreatedAt: row.CreatedAt})
+	}
+	respondWithJSON(w, 200, newUserPage(entries, page.limit))
+}
+
+func (cfg *apiConfig) getMutedUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeFollowsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	rows, err := cfg.database.GetMutedUsers(ctx, database.GetMutedUsersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt,
+		CursorID:        page.cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve muted users")
+		return
+	}
+	var entries []RelationEntry
+	for _, row := range rows {
+		entries = append(entries, RelationEntry{UserID: row.MutedID, CreatedAt: row.CreatedAt})
+	}
+	respondWithJSON(w, 200, newUserPage(entries, page.limit))
+}
@@ -0,0 +1,62 @@
+// Command promote-admin grants the admin role to an existing account. It is
+// meant for bootstrapping a fresh deployment, so by default it refuses to run
+// once any admin exists; later changes go through PUT /admin/users/{id}/role.
+//
+//	go run ./cmd/promote-admin [-force] user@example.com
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	force := flag.Bool("force", false, "promote even if an admin already exists")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: promote-admin [-force] email\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	email := flag.Arg(0)
+
+	godotenv.Load()
+	db, err := sql.Open("postgres", os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatalf("Error: opening database: %v", err)
+	}
+	defer db.Close()
+	queries := database.New(db)
+	ctx := context.Background()
+
+	admins, err := queries.CountAdmins(ctx)
+	if err != nil {
+		log.Fatalf("Error: counting admins: %v", err)
+	}
+	if admins > 0 && !*force {
+		log.Fatalf("Error: %d admin(s) already exist; pass -force to promote anyway", admins)
+	}
+	rows, err := queries.SetUserRoleByEmail(ctx, database.SetUserRoleByEmailParams{
+		Role:  auth.RoleAdmin,
+		Email: email,
+	})
+	if err != nil {
+		log.Fatalf("Error: updating role: %v", err)
+	}
+	if rows == 0 {
+		log.Fatalf("Error: no user with email %s", email)
+	}
+	fmt.Printf("%s is now an admin; they must sign in again to pick up the role\n", email)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+type FollowEntry struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (e FollowEntry) position() (time.Time, uuid.UUID) {
+	return e.FollowedAt, e.UserID
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	followerID, err := cfg.authorizedUser(r, scopeFollowsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	if followeeID == followerID {
+		respondWithError(w, 400, "Unable to follow yourself")
+		return
+	}
+	_, err = cfg.database.GetUser(ctx, followeeID)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	blocked, err := cfg.blockedBetween(ctx, followerID, followeeID)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to follow user")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, errBlocked.Error())
+		return
+	}
+	err = cfg.database.FollowUser(ctx, database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to follow user")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	followerID, err := cfg.authorizedUser(r, scopeFollowsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	err = cfg.database.UnfollowUser(ctx, database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to unfollow user")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getFollowers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	rows, err := cfg.database.GetFollowers(ctx, database.GetFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt,
+		CursorID:        page.cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve followers")
+		return
+	}
+	var entries []FollowEntry
+	for _, row := range rows {
+		entries = append(entries, FollowEntry{UserID: row.FollowerID, FollowedAt: row.CreatedAt})
+	}
+	respondWithJSON(w, 200, newUserPage(entries, page.limit))
+}
+
+func (cfg *apiConfig) getFollowing(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	rows, err := cfg.database.GetFollowing(ctx, database.GetFollowingParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt,
+		CursorID:        page.cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve followed users")
+		return
+	}
+	var entries []FollowEntry
+	for _, row := range rows {
+		entries = append(entries, FollowEntry{UserID: row.FolloweeID, FollowedAt: row.CreatedAt})
+	}
+	respondWithJSON(w, 200, newUserPage(entries, page.limit))
+}
+
+func (cfg *apiConfig) getTimeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	page, err := parsePageParams(query)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	var dbChirps []database.Chirp
+	if query.Get("sort") == "desc" {
+		dbChirps, err = cfg.database.GetTimelineDesc(ctx, database.GetTimelineDescParams{
+			FollowerID:      userID,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	} else {
+		dbChirps, err = cfg.database.GetTimeline(ctx, database.GetTimelineParams{
+			FollowerID:      userID,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 400, "Unable to retrieve timeline")
+		return
+	}
+	chirps, err := cfg.newChirpPage(ctx, dbChirps, page.limit, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve timeline")
+		return
+	}
+	respondWithJSON(w, 200, chirps)
+}
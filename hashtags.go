@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/chirptext"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+	maxTrendingHashtags   = 20
+)
+
+type TrendingHashtag struct {
+	Tag  string `json:"tag"`
+	Uses int64  `json:"uses"`
+}
+
+func (cfg *apiConfig) getHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tag := chirptext.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, 404, "")
+		return
+	}
+	query := r.URL.Query()
+	page, err := parsePageParams(query)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	var dbChirps []database.Chirp
+	viewer := cfg.optionalViewer(r)
+	if query.Get("sort") == "desc" {
+		dbChirps, err = cfg.database.GetHashtagChirpsDesc(ctx, database.GetHashtagChirpsDescParams{
+			Tag:             tag,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			ViewerID:        viewer,
+			PageSize:        page.fetchSize(),
+		})
+	} else {
+		dbChirps, err = cfg.database.GetHashtagChirps(ctx, database.GetHashtagChirpsParams{
+			Tag:             tag,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			ViewerID:        viewer,
+			PageSize:        page.fetchSize(),
+		})
+	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 400, "Unable to retrieve Chirps")
+		return
+	}
+	chirps, err := cfg.newChirpPage(ctx, dbChirps, page.limit, viewer)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve Chirps")
+		return
+	}
+	respondWithJSON(w, 200, chirps)
+}
+
+func (cfg *apiConfig) getTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	window := cfg.trendingWindow
+	if param := r.URL.Query().Get("window"); param != "" {
+		parsed, err := time.ParseDuration(param)
+		if err != nil || parsed <= 0 {
+			respondWithError(w, 400, "Invalid window")
+			return
+		}
+		window = min(parsed, maxTrendingWindow)
+	}
+	rows, err := cfg.database.GetTrendingHashtags(ctx, database.GetTrendingHashtagsParams{
+		Since:   time.Now().UTC().Add(-window),
+		MaxTags: maxTrendingHashtags,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve trending hashtags")
+		return
+	}
+	trending := []TrendingHashtag{}
+	for _, row := range rows {
+		trending = append(trending, TrendingHashtag{Tag: row.Tag, Uses: row.Uses})
+	}
+	respondWithJSON(w, 200, trending)
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -10,49 +11,57 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hashString, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashString), nil
-}
+const tokenIssuer = "chirpy"
 
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
+func MakeJWT(userID uuid.UUID, role string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Role: role,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims(claims))
-	tokenString, err := token.SignedString([]byte(tokenSecret))
+	tokenString, err := keys.sign(claims)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+func ValidateJWT(tokenString string, keys *KeySet) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTWithRole(tokenString, keys)
+	return userID, err
+}
+
+// ValidateJWTWithRole also returns the role the token was issued with.
+// Tokens minted before roles existed carry none and count as RoleUser.
+func ValidateJWTWithRole(tokenString string, keys *KeySet) (uuid.UUID, string, error) {
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, "", err
+	}
+	if !claims.VerifyIssuer(tokenIssuer, true) {
+		return uuid.UUID{}, "", fmt.Errorf("unexpected issuer %q", claims.Issuer)
 	}
 	subject, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, "", err
+	}
+	role := claims.Role
+	if role == "" {
+		role = RoleUser
 	}
-	return subject, nil
+	return subject, role, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -73,6 +82,13 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(slice), nil
 }
 
+// HashToken returns the hex SHA-256 of a random token, for storing tokens
+// that only ever need to be looked up, never read back.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	auth := headers.Get("Authorization")
 	if !strings.HasPrefix(auth, "ApiKey ") {
@@ -81,3 +97,14 @@ func GetAPIKey(headers http.Header) (string, error) {
 	stringToken := strings.TrimPrefix(auth, "ApiKey ")
 	return stringToken, nil
 }
+
+// GetPersonalToken extracts a personal access token sent as
+// "Authorization: Token <token>".
+func GetPersonalToken(headers http.Header) (string, error) {
+	auth := headers.Get("Authorization")
+	if !strings.HasPrefix(auth, "Token ") {
+		return "", fmt.Errorf("401 unauthorized")
+	}
+	stringToken := strings.TrimPrefix(auth, "Token ")
+	return stringToken, nil
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	// private is nil for keys that are only kept around to verify tokens
+	// signed before a rotation.
+	private interface{}
+	public  interface{}
+}
+
+// KeySet holds the key access tokens are signed with and every key they may
+// still be verified against, looked up by the token's kid header.
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// NewHMACKeySet signs and verifies with a single shared HS256 secret. It is
+// the fallback when no key directory is configured, and publishes no JWKS.
+func NewHMACKeySet(secret string) *KeySet {
+	key := &signingKey{
+		method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+	return &KeySet{active: key, keys: map[string]*signingKey{"": key}}
+}
+
+// LoadKeySet reads every *.pem file in dir as an RSA or Ed25519 key whose kid
+// is the file name without the extension. Private keys can sign and verify;
+// public keys only verify, which is how a retired key is kept until the
+// tokens it signed have expired. activeKID picks the signing key; when it is
+// empty the private key with the greatest kid is used, so date-named keys
+// rotate by dropping a new file in.
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeySet{keys: map[string]*signingKey{}}
+	signers := []string{}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		ks.keys[kid] = key
+		if key.private != nil {
+			signers = append(signers, kid)
+		}
+	}
+	if activeKID == "" && len(signers) > 0 {
+		sort.Strings(signers)
+		activeKID = signers[len(signers)-1]
+	}
+	active, ok := ks.keys[activeKID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("no private signing key %q in %s", activeKID, dir)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+func parseKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, public: k}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", parsed)
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	if ks.active.kid != "" {
+		token.Header["kid"] = ks.active.kid
+	}
+	return token.SignedString(ks.active.private)
+}
+
+// keyFunc resolves the verification key by kid and refuses any token whose
+// alg differs from that key's, so an RSA public key can never be used as an
+// HMAC secret.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// JSONWebKey is the public half of a verification key in RFC 7517 form.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS lists the public verification keys, sorted by kid. Shared HMAC
+// secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JSONWebKey{}}
+	for _, key := range ks.keys {
+		jwk := JSONWebKey{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
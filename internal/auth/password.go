@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2Params are the Argon2id settings for new hashes. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP recommendation of 64 MiB of memory,
+// three passes and two lanes.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var (
+	argon2Mu     sync.RWMutex
+	argon2Params = DefaultArgon2Params
+)
+
+var errUnknownHashFormat = errors.New("unrecognized password hash format")
+
+// SetArgon2Params changes the parameters used for new hashes. Existing hashes
+// made with other parameters still verify and are reported by NeedsRehash.
+func SetArgon2Params(params Argon2Params) {
+	argon2Mu.Lock()
+	defer argon2Mu.Unlock()
+	argon2Params = params
+}
+
+func currentArgon2Params() Argon2Params {
+	argon2Mu.RLock()
+	defer argon2Mu.RUnlock()
+	return argon2Params
+}
+
+// Argon2ParamsFromEnv overrides the defaults with ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM when they are set.
+func Argon2ParamsFromEnv() (Argon2Params, error) {
+	params := DefaultArgon2Params
+	if v := os.Getenv("ARGON2_MEMORY_KIB"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || n < 8*1024 {
+			return params, fmt.Errorf("invalid ARGON2_MEMORY_KIB %q", v)
+		}
+		params.Memory = uint32(n)
+	}
+	if v := os.Getenv("ARGON2_ITERATIONS"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || n < 1 {
+			return params, fmt.Errorf("invalid ARGON2_ITERATIONS %q", v)
+		}
+		params.Iterations = uint32(n)
+	}
+	if v := os.Getenv("ARGON2_PARALLELISM"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || n < 1 {
+			return params, fmt.Errorf("invalid ARGON2_PARALLELISM %q", v)
+		}
+		params.Parallelism = uint8(n)
+	}
+	return params, nil
+}
+
+// hashFormat is one password hash encoding we can verify.
+type hashFormat interface {
+	matches(hash string) bool
+	verify(password, hash string) error
+	needsRehash(hash string) bool
+}
+
+var hashFormats = []hashFormat{argon2idFormat{}, bcryptFormat{}}
+
+func formatOf(hash string) (hashFormat, error) {
+	for _, format := range hashFormats {
+		if format.matches(hash) {
+			return format, nil
+		}
+	}
+	return nil, errUnknownHashFormat
+}
+
+// HashPassword hashes with Argon2id and the configured parameters, encoded
+// as a PHC string.
+func HashPassword(password string) (string, error) {
+	params := currentArgon2Params()
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPasswordHash verifies a password against any supported hash format.
+func CheckPasswordHash(password, hash string) error {
+	format, err := formatOf(hash)
+	if err != nil {
+		return err
+	}
+	return format.verify(password, hash)
+}
+
+// NeedsRehash reports whether a hash that just verified should be replaced
+// with a fresh HashPassword result: it is not Argon2id, or it was made with
+// different parameters.
+func NeedsRehash(hash string) bool {
+	format, err := formatOf(hash)
+	if err != nil {
+		return false
+	}
+	return format.needsRehash(hash)
+}
+
+type bcryptFormat struct{}
+
+func (bcryptFormat) matches(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (bcryptFormat) verify(password, hash string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+func (bcryptFormat) needsRehash(hash string) bool {
+	return true
+}
+
+type argon2idFormat struct{}
+
+func (argon2idFormat) matches(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+type argon2idHash struct {
+	params Argon2Params
+	salt   []byte
+	key    []byte
+}
+
+func parseArgon2id(hash string) (argon2idHash, error) {
+	// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return argon2idHash{}, errUnknownHashFormat
+	}
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return argon2idHash{}, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	parsed := argon2idHash{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parsed.params.Memory, &parsed.params.Iterations, &parsed.params.Parallelism)
+	if err != nil {
+		return argon2idHash{}, fmt.Errorf("malformed argon2 parameters %q", parts[3])
+	}
+	parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2idHash{}, err
+	}
+	parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2idHash{}, err
+	}
+	parsed.params.SaltLength = uint32(len(parsed.salt))
+	parsed.params.KeyLength = uint32(len(parsed.key))
+	return parsed, nil
+}
+
+func (argon2idFormat) verify(password, hash string) error {
+	parsed, err := parseArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	p := parsed.params
+	key := argon2.IDKey([]byte(password), parsed.salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, parsed.key) != 1 {
+		return errors.New("password does not match")
+	}
+	return nil
+}
+
+func (argon2idFormat) needsRehash(hash string) bool {
+	parsed, err := parseArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return parsed.params != currentArgon2Params()
+}
+
+const (
+	MinPasswordLength = 12
+	MaxPasswordLength = 128
+)
+
+// commonPasswords is a short deny list of the passwords that top every
+// breach corpus and would otherwise pass the length rule.
+var commonPasswords = map[string]bool{
+	"123456789012": true, "1234567890123": true, "password1234": true,
+	"passwordpassword": true, "qwertyuiopas": true, "qwerty123456": true,
+	"iloveyou1234": true, "111111111111": true, "000000000000": true,
+	"abcdefghijkl": true, "abc123abc123": true, "letmeinletmein": true,
+	"welcome12345": true, "adminadmin12": true, "chirpychirpy": true,
+	"password123!": true, "qwertyqwerty": true, "1q2w3e4r5t6y": true,
+}
+
+// CheckPasswordStrength applies the password policy: a length between
+// MinPasswordLength and MaxPasswordLength characters, not a single repeated
+// character, not a well-known password, and not containing any of the
+// account's own identifiers, such as its username or the local part of its
+// email address.
+func CheckPasswordStrength(password string, userInputs ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < MinPasswordLength {
+		return fmt.Errorf("Password must be at least %d characters", MinPasswordLength)
+	}
+	if length > MaxPasswordLength {
+		return fmt.Errorf("Password must be at most %d characters", MaxPasswordLength)
+	}
+	lower := strings.ToLower(password)
+	first, _ := utf8.DecodeRuneInString(lower)
+	if strings.Trim(lower, string(first)) == "" {
+		return errors.New("Password must not be a single repeated character")
+	}
+	if commonPasswords[lower] {
+		return errors.New("Password is too common")
+	}
+	for _, input := range userInputs {
+		input, _, _ = strings.Cut(strings.ToLower(input), "@")
+		if len(input) >= 3 && strings.Contains(lower, input) {
+			return errors.New("Password must not contain your username or email")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// testArgon2Params are small enough to keep the tests fast.
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const (
+	testPassword = "correct horse battery"
+	// testArgon2Hash is testPassword hashed with testArgon2Params and the
+	// salt "somesaltsomesalt".
+	testArgon2Hash = "$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$Tikn65VjNKv9r3419wmSJI1jHoKt7UrMWJlLmO7lXpU"
+	testBcryptHash = "$2a$04$mir1r1rK70rFM8M5Y3wcku667WRKIM6hIw1xOUXaqLycAyHTlMeIS"
+)
+
+func withArgon2Params(t *testing.T, params Argon2Params) {
+	t.Helper()
+	previous := currentArgon2Params()
+	SetArgon2Params(params)
+	t.Cleanup(func() { SetArgon2Params(previous) })
+}
+
+func TestParseArgon2id(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		want    Argon2Params
+		wantErr bool
+	}{
+		{name: "valid", hash: testArgon2Hash, want: testArgon2Params},
+		{name: "too few fields", hash: "$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ", wantErr: true},
+		{name: "wrong version", hash: strings.Replace(testArgon2Hash, "v=19", "v=16", 1), wantErr: true},
+		{name: "malformed parameters", hash: strings.Replace(testArgon2Hash, "m=1024,t=1,p=1", "m=1024", 1), wantErr: true},
+		{name: "bad salt encoding", hash: strings.Replace(testArgon2Hash, "c29tZXNhbHRzb21lc2FsdA", "not base64!", 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseArgon2id(tt.hash)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseArgon2id succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArgon2id: %v", err)
+			}
+			if parsed.params != tt.want {
+				t.Errorf("params = %+v, want %+v", parsed.params, tt.want)
+			}
+			if string(parsed.salt) != "somesaltsomesalt" {
+				t.Errorf("salt = %q", parsed.salt)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordHash(t *testing.T) {
+	withArgon2Params(t, testArgon2Params)
+	fresh, err := HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		wantErr  bool
+	}{
+		{name: "fixed argon2id hash", password: testPassword, hash: testArgon2Hash},
+		{name: "fixed argon2id hash, wrong password", password: "wrong horse battery", hash: testArgon2Hash, wantErr: true},
+		{name: "fresh argon2id hash", password: testPassword, hash: fresh},
+		{name: "fresh argon2id hash, wrong password", password: "wrong horse battery", hash: fresh, wantErr: true},
+		{name: "legacy bcrypt hash", password: testPassword, hash: testBcryptHash},
+		{name: "legacy bcrypt hash, wrong password", password: "wrong horse battery", hash: testBcryptHash, wantErr: true},
+		{name: "unknown format", password: testPassword, hash: "$md5$abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordHash(tt.password, tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPasswordHash error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	withArgon2Params(t, testArgon2Params)
+	stronger := testArgon2Params
+	stronger.Iterations = 2
+	tests := []struct {
+		name   string
+		params Argon2Params
+		hash   string
+		want   bool
+	}{
+		{name: "current parameters", params: testArgon2Params, hash: testArgon2Hash, want: false},
+		{name: "parameters changed", params: stronger, hash: testArgon2Hash, want: true},
+		{name: "bcrypt", params: testArgon2Params, hash: testBcryptHash, want: true},
+		{name: "unknown format", params: testArgon2Params, hash: "$md5$abc", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetArgon2Params(tt.params)
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordStrength(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		wantErr    bool
+	}{
+		{name: "strong", password: testPassword, userInputs: []string{"alice@example.com", "alice"}},
+		{name: "too short", password: "short", wantErr: true},
+		{name: "too long", password: strings.Repeat("ab", MaxPasswordLength), wantErr: true},
+		{name: "repeated character", password: strings.Repeat("z", 16), wantErr: true},
+		{name: "common", password: "Password1234", wantErr: true},
+		{name: "contains username", password: "alice-likes-chirps", userInputs: []string{"alice"}, wantErr: true},
+		{name: "contains email local part", password: "ALICE.SMITH-2026", userInputs: []string{"alice.smith@example.com"}, wantErr: true},
+		{name: "short inputs ignored", password: "bo and the chirps", userInputs: []string{"bo"}},
+		{name: "empty username ignored", password: testPassword, userInputs: []string{"bob@example.com", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordStrength(tt.password, tt.userInputs...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPasswordStrength error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const PurposeEmailVerification = "email-verification"
+
+// MinSecretLength is the shortest HMAC secret accepted for signing tokens.
+const MinSecretLength = 32
+
+var errShortSecret = fmt.Errorf("token secret must be at least %d bytes", MinSecretLength)
+
+type purposeClaims struct {
+	jwt.RegisteredClaims
+	Binding string `json:"bnd,omitempty"`
+}
+
+// purposeKey derives a separate HMAC key per purpose, so a token minted for
+// one flow never validates as an access token or as a token for another flow.
+func purposeKey(tokenSecret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte("chirpy:" + purpose))
+	return mac.Sum(nil)
+}
+
+// MakePurposeJWT signs a short-lived token that is only accepted by
+// ValidatePurposeJWT for the same purpose. The binding is echoed back on
+// validation so callers can tie the token to state such as an email address.
+func MakePurposeJWT(userID uuid.UUID, purpose, binding, tokenSecret string, expiresIn time.Duration) (string, error) {
+	if len(tokenSecret) < MinSecretLength {
+		return "", errShortSecret
+	}
+	claims := purposeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{purpose},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Binding: binding,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(purposeKey(tokenSecret, purpose))
+}
+
+func ValidatePurposeJWT(tokenString, purpose, tokenSecret string) (uuid.UUID, string, error) {
+	if len(tokenSecret) < MinSecretLength {
+		return uuid.UUID{}, "", errShortSecret
+	}
+	claims := &purposeClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return purposeKey(tokenSecret, purpose), nil
+	})
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+	if !claims.VerifyAudience(purpose, true) {
+		return uuid.UUID{}, "", fmt.Errorf("token is not for %s", purpose)
+	}
+	subject, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+	return subject, claims.Binding, nil
+}
@@ -0,0 +1,26 @@
+package auth
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether a user with role may act as required. Roles are
+// ordered, so admins can do everything moderators can.
+func HasRole(role, required string) bool {
+	have, ok := roleRanks[role]
+	return ok && have >= roleRanks[required]
+}
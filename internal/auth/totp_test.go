@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed "12345678901234567890" used by the RFC 4226
+// and RFC 6238 test vectors, base32-encoded.
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D.
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		if got := hotp([]byte("12345678901234567890"), int64(counter)); got != code {
+			t.Errorf("hotp(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, keeping the last six of its eight digits.
+	tests := []struct {
+		unix int64
+		code string
+		step int64
+	}{
+		{unix: 59, code: "287082", step: 1},
+		{unix: 1111111109, code: "081804", step: 37037036},
+		{unix: 1111111111, code: "050471", step: 37037037},
+		{unix: 1234567890, code: "005924", step: 41152263},
+		{unix: 2000000000, code: "279037", step: 66666666},
+		{unix: 20000000000, code: "353130", step: 666666666},
+	}
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.step {
+			t.Errorf("ValidateTOTP(%s at %d) = (%d, %v), want (%d, true)", tt.code, tt.unix, step, ok, tt.step)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// 287082 is the code for step 1, which covers seconds 30 to 59.
+	tests := []struct {
+		name   string
+		unix   int64
+		secret string
+		code   string
+		want   bool
+	}{
+		{name: "same step", unix: 45, secret: rfcSecret, code: "287082", want: true},
+		{name: "one step early", unix: 15, secret: rfcSecret, code: "287082", want: true},
+		{name: "one step late", unix: 75, secret: rfcSecret, code: "287082", want: true},
+		{name: "two steps late", unix: 95, secret: rfcSecret, code: "287082", want: false},
+		{name: "surrounding spaces", unix: 45, secret: rfcSecret, code: " 287082 ", want: true},
+		{name: "lowercase secret", unix: 45, secret: strings.ToLower(rfcSecret), code: "287082", want: true},
+		{name: "wrong code", unix: 45, secret: rfcSecret, code: "123456", want: false},
+		{name: "empty code", unix: 45, secret: rfcSecret, code: "", want: false},
+		{name: "invalid secret", unix: 45, secret: "not base32!", code: "287082", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.unix, 0))
+			if ok != tt.want {
+				t.Errorf("ValidateTOTP = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI(rfcSecret, "user@example.com", "Chirpy")
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/Chirpy:user@example.com" {
+		t.Errorf("URI = %s", uri)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Chirpy" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("URI parameters = %v", query)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("recovery code %q is not of the form xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q repeated", code)
+		}
+		seen[code] = true
+		typed := strings.ToUpper(strings.Replace(code, "-", " ", 1))
+		if HashRecoveryCode(typed) != HashRecoveryCode(code) {
+			t.Errorf("HashRecoveryCode does not normalize %q", typed)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const MaxHashtagLength = 64
+
+// NormalizeHashtag folds a tag to the form it is stored and looked up in:
+// NFKC-normalized, lower case and without the leading '#'.
+func NormalizeHashtag(tag string) string {
+	tag = strings.TrimPrefix(tag, "#")
+	return strings.ToLower(norm.NFKC.String(tag))
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+// inURL reports whether the rune at i belongs to a URL, judged by a "://"
+// earlier in the same whitespace-delimited word.
+func inURL(runes []rune, i int) bool {
+	start := i
+	for start > 0 && !unicode.IsSpace(runes[start-1]) {
+		start--
+	}
+	return strings.Contains(string(runes[start:i]), "://")
+}
+
+// ExtractHashtags returns the distinct normalized #tags in a chirp body, in
+// the order they first appear. A tag must start a word outside a URL, contain
+// at least one letter and be no longer than MaxHashtagLength runes.
+func ExtractHashtags(body string) []string {
+	runes := []rune(norm.NFKC.String(body))
+	seen := map[string]bool{}
+	tags := []string{}
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isTagRune(runes[i-1])) || inURL(runes, i) {
+			continue
+		}
+		end := i + 1
+		hasLetter := false
+		for end < len(runes) && isTagRune(runes[end]) {
+			if unicode.IsLetter(runes[end]) {
+				hasLetter = true
+			}
+			end++
+		}
+		length := end - i - 1
+		if hasLetter && length <= MaxHashtagLength {
+			tag := NormalizeHashtag(string(runes[i+1 : end]))
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		i = end - 1
+	}
+	return tags
+}
@@ -0,0 +1,60 @@
+package chirptext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "single", body: "hello #Chirpy", want: []string{"chirpy"}},
+		{name: "case folded", body: "#GoLang", want: []string{"golang"}},
+		{name: "duplicates", body: "#go #Go #GO and #rust #go", want: []string{"go", "rust"}},
+		{name: "trailing punctuation", body: "#chirpy, #go! (#rust). #zig?", want: []string{"chirpy", "go", "rust", "zig"}},
+		{name: "underscores and digits", body: "#snake_case #2026goals", want: []string{"snake_case", "2026goals"}},
+		{name: "digits only", body: "issue #42", want: []string{}},
+		{name: "embedded in word", body: "email#tag and c#", want: []string{}},
+		{name: "url fragment", body: "https://example.com/#section", want: []string{}},
+		{name: "url path fragment", body: "https://example.com/page#section", want: []string{}},
+		{name: "after url", body: "see https://example.com/#top for #news", want: []string{"news"}},
+		{name: "fullwidth", body: "#ｇｏ", want: []string{"go"}},
+		{name: "accents kept", body: "#Café", want: []string{"café"}},
+		{name: "longest", body: "#" + strings.Repeat("a", MaxHashtagLength), want: []string{strings.Repeat("a", MaxHashtagLength)}},
+		{name: "too long", body: "#" + strings.Repeat("a", MaxHashtagLength+1), want: []string{}},
+		{name: "bare hash", body: "# #", want: []string{}},
+		{name: "empty", body: "", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractHashtags(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractHashtags(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "#Chirpy", want: "chirpy"},
+		{tag: "chirpy", want: "chirpy"},
+		{tag: "GO", want: "go"},
+		{tag: "#ＧＯ", want: "go"},
+		{tag: "#Café", want: "café"},
+		{tag: "Cafe\u0301", want: "café"},
+		{tag: "##go", want: "#go"},
+		{tag: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeHashtag(tt.tag); got != tt.want {
+			t.Errorf("NormalizeHashtag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
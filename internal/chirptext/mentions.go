@@ -0,0 +1,75 @@
+package chirptext
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+// Mention is an @username found in a chirp body. Start and End are rune
+// offsets into the body, covering the '@' and the username.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// IsUsernameRune reports whether r may appear in a username. Usernames are
+// limited to ASCII letters, digits and underscores so they can be typed and
+// compared without normalization.
+func IsUsernameRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
+
+// ExtractMentions returns every @username in a chirp body, lower-cased for
+// case-insensitive lookup. An '@' that follows a letter or digit, as in an
+// email address, or that sits inside a URL does not start a mention.
+func ExtractMentions(body string) []Mention {
+	runes := []rune(body)
+	mentions := []Mention{}
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && (isTagRune(runes[i-1]) || runes[i-1] == '@')) || inURL(runes, i) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && IsUsernameRune(runes[end]) {
+			end++
+		}
+		length := end - i - 1
+		if length >= MinUsernameLength && length <= MaxUsernameLength {
+			mentions = append(mentions, Mention{
+				Username: strings.ToLower(string(runes[i+1 : end])),
+				Start:    i,
+				End:      end,
+			})
+		}
+		i = end - 1
+	}
+	return mentions
+}
+
+// ValidateUsername checks a username against the rules that keep it
+// mentionable: 3 to 30 ASCII letters, digits or underscores, with at least
+// one letter.
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return fmt.Errorf("Username must be %d to %d characters long", MinUsernameLength, MaxUsernameLength)
+	}
+	hasLetter := false
+	for _, r := range username {
+		if !IsUsernameRune(r) {
+			return fmt.Errorf("Username may only contain letters, digits and underscores")
+		}
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			hasLetter = true
+		}
+	}
+	if !hasLetter {
+		return fmt.Errorf("Username must contain a letter")
+	}
+	return nil
+}
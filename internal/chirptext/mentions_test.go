@@ -0,0 +1,73 @@
+package chirptext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Mention
+	}{
+		{name: "single", body: "hi @Alice", want: []Mention{{Username: "alice", Start: 3, End: 9}}},
+		{name: "start of body", body: "@bob_99 hi", want: []Mention{{Username: "bob_99", Start: 0, End: 7}}},
+		{name: "punctuation boundaries", body: "(@alice), @bob! @carol.", want: []Mention{
+			{Username: "alice", Start: 1, End: 7},
+			{Username: "bob", Start: 10, End: 14},
+			{Username: "carol", Start: 16, End: 22},
+		}},
+		{name: "duplicates kept", body: "@alice @ALICE", want: []Mention{
+			{Username: "alice", Start: 0, End: 6},
+			{Username: "alice", Start: 7, End: 13},
+		}},
+		{name: "email address", body: "mail a@b or alice@example.com", want: []Mention{}},
+		{name: "double at", body: "@@alice", want: []Mention{}},
+		{name: "url", body: "https://social.example/@alice", want: []Mention{}},
+		{name: "after url", body: "https://social.example/@alice via @bob", want: []Mention{{Username: "bob", Start: 34, End: 38}}},
+		{name: "rune offsets", body: "héllo @alice", want: []Mention{{Username: "alice", Start: 6, End: 12}}},
+		{name: "too short", body: "@al", want: []Mention{}},
+		{name: "shortest", body: "@ali", want: []Mention{{Username: "ali", Start: 0, End: 4}}},
+		{name: "longest", body: "@" + strings.Repeat("a", MaxUsernameLength), want: []Mention{
+			{Username: strings.Repeat("a", MaxUsernameLength), Start: 0, End: MaxUsernameLength + 1},
+		}},
+		{name: "too long", body: "@" + strings.Repeat("a", MaxUsernameLength+1), want: []Mention{}},
+		{name: "bare at", body: "@ @", want: []Mention{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractMentions(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		wantErr  bool
+	}{
+		{username: "alice"},
+		{username: "Bob_99"},
+		{username: "abc"},
+		{username: strings.Repeat("a", MaxUsernameLength)},
+		{username: "ab", wantErr: true},
+		{username: strings.Repeat("a", MaxUsernameLength+1), wantErr: true},
+		{username: "", wantErr: true},
+		{username: "12345", wantErr: true},
+		{username: "___", wantErr: true},
+		{username: "al-ice", wantErr: true},
+		{username: "al ice", wantErr: true},
+		{username: "alice@example", wantErr: true},
+		{username: "alicé", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := ValidateUsername(tt.username)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateUsername(%q) error = %v, wantErr %v", tt.username, err, tt.wantErr)
+		}
+	}
+}
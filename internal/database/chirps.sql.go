@@ -7,29 +7,52 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpDependents = `-- name: CountChirpDependents :one
+SELECT COUNT(*) FROM chirps
+WHERE in_reply_to = $1::uuid OR quote_of = $1::uuid
+`
+
+func (q *Queries) CountChirpDependents(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpDependents, chirpID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.QuoteOf,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +60,45 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
@@ -50,8 +112,35 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+DELETE FROM chirps WHERE user_id = $1 AND rechirp_of = $2
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps WHERE rechirp_of = $1
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, rechirpOf uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOf, rechirpOf)
+	return err
+}
+
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps WHERE id = $1
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -63,16 +152,271 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, in_reply_to, depth) AS (
+    SELECT c.id, c.in_reply_to, 0 FROM chirps c WHERE c.id = $1::uuid
+    UNION ALL
+    SELECT p.id, p.in_reply_to, a.depth + 1
+    FROM chirps p JOIN ancestors a ON p.id = a.in_reply_to
+    WHERE a.depth < $2::int
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of,
+    chirp_visible_to(chirps.user_id, $3::uuid) AS visible
+FROM chirps
+WHERE chirps.id IN (SELECT ancestors.id FROM ancestors WHERE ancestors.depth > 0)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+`
+
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID
+	MaxDepth int32
+	ViewerID uuid.NullUUID
+}
+
+type GetChirpAncestorsRow struct {
+	Chirp   Chirp
+	Visible bool
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.MaxDepth, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Visible,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants(id, depth) AS (
+    SELECT c.id, 1 FROM chirps c WHERE c.in_reply_to = $1::uuid
+    UNION ALL
+    SELECT r.id, d.depth + 1
+    FROM chirps r JOIN descendants d ON r.in_reply_to = d.id
+    WHERE d.depth < $2::int
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of,
+    chirp_visible_to(chirps.user_id, $3::uuid) AS visible
+FROM chirps
+WHERE chirps.id IN (SELECT descendants.id FROM descendants)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type GetChirpDescendantsParams struct {
+	ChirpID   uuid.UUID
+	MaxDepth  int32
+	ViewerID  uuid.NullUUID
+	MaxChirps int32
+}
+
+type GetChirpDescendantsRow struct {
+	Chirp   Chirp
+	Visible bool
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants,
+		arg.ChirpID,
+		arg.MaxDepth,
+		arg.ViewerID,
+		arg.MaxChirps,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Visible,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getChirpForViewer = `-- name: GetChirpForViewer :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of,
+    chirp_visible_to(chirps.user_id, $1::uuid) AS visible
+FROM chirps
+WHERE chirps.id = $2
+`
+
+type GetChirpForViewerParams struct {
+	ViewerID uuid.NullUUID
+	ID       uuid.UUID
+}
+
+type GetChirpForViewerRow struct {
+	Chirp   Chirp
+	Visible bool
+}
+
+func (q *Queries) GetChirpForViewer(ctx context.Context, arg GetChirpForViewerParams) (GetChirpForViewerRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForViewer, arg.ViewerID, arg.ID)
+	var i GetChirpForViewerRow
+	err := row.Scan(
+		&i.Chirp.ID,
+		&i.Chirp.CreatedAt,
+		&i.Chirp.UpdatedAt,
+		&i.Chirp.Body,
+		&i.Chirp.UserID,
+		&i.Chirp.InReplyTo,
+		&i.Chirp.DeletedAt,
+		&i.Chirp.RechirpOf,
+		&i.Chirp.QuoteOf,
+		&i.Visible,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1)
+  AND ($2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid))
+  AND chirp_visible_to(chirps.user_id, $4::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type GetChirpsParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps
+WHERE id = ANY($1::uuid[])
+  AND chirp_visible_to(chirps.user_id, $2::uuid)
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +430,10 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -101,11 +449,33 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsDesc = `-- name: GetChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at DESC
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1)
+  AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid))
+  AND chirp_visible_to(chirps.user_id, $4::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
 `
 
-func (q *Queries) GetChirpsDesc(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsDesc)
+type GetChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpsDesc(ctx context.Context, arg GetChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +489,10 @@ func (q *Queries) GetChirpsDesc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -133,12 +507,61 @@ func (q *Queries) GetChirpsDesc(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getUserChirps = `-- name: GetUserChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps WHERE user_id = $1 AND rechirp_of = $2
 `
 
-func (q *Queries) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getUserChirps, userID)
+type GetRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = $1
+  AND chirps.deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($2::timestamp, $3::uuid))
+  AND chirp_visible_to(chirps.user_id, $1::uuid)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	FollowerID      uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.FollowerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -152,6 +575,10 @@ func (q *Queries) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -166,12 +593,34 @@ func (q *Queries) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp,
 	return items, nil
 }
 
-const getUserChirpsDesc = `-- name: GetUserChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at DESC
+const getTimelineDesc = `-- name: GetTimelineDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = $1
+  AND chirps.deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+  AND chirp_visible_to(chirps.user_id, $1::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
 `
 
-func (q *Queries) GetUserChirpsDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getUserChirpsDesc, userID)
+type GetTimelineDescParams struct {
+	FollowerID      uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetTimelineDesc(ctx context.Context, arg GetTimelineDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimelineDesc,
+		arg.FollowerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -185,6 +634,10 @@ func (q *Queries) GetUserChirpsDesc(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -198,3 +651,99 @@ func (q *Queries) GetUserChirpsDesc(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+WHERE chirps.search_vector @@ to_tsquery('english', $1)
+  AND chirps.deleted_at IS NULL
+  AND ($2::uuid IS NULL OR chirps.user_id = $2)
+  AND chirp_visible_to(chirps.user_id, $3::uuid)
+ORDER BY ts_rank(chirps.search_vector, to_tsquery('english', $1)) DESC,
+    chirps.created_at DESC, chirps.id DESC
+LIMIT $4 OFFSET $5
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	AuthorID   uuid.NullUUID
+	ViewerID   uuid.NullUUID
+	PageSize   int32
+	PageOffset int32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.PageSize,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $1, updated_at = NOW() WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+`
+
+type UpdateChirpBodyParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
@@ -11,28 +11,175 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	DeletedAt sql.NullTime
+	RechirpOf uuid.NullUUID
+	QuoteOf   uuid.NullUUID
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+	CreatedAt   time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginAttempt struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+}
+
+type ModerationDecision struct {
+	ID             uuid.UUID
+	ReportID       uuid.UUID
+	ModeratorID    uuid.NullUUID
+	Action         string
+	Note           string
+	SuspendedUntil sql.NullTime
+	CreatedAt      time.Time
+}
+
+type ModerationWord struct {
+	Word      string
+	Action    string
+	CreatedAt time.Time
+}
+
+type OidcState struct {
+	StateHash    string
+	CodeVerifier string
+	Nonce        string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
 }
 
-type User struct {
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	UserAgent   string
+	IpAddress   string
+	LastUsedAt  time.Time
+}
+
+type Report struct {
 	ID             uuid.UUID
+	ReporterID     uuid.NullUUID
+	ChirpID        uuid.NullUUID
+	ChirpBody      sql.NullString
+	ReportedUserID uuid.UUID
+	Reason         string
+	Details        string
+	Status         string
+	ClaimedBy      uuid.NullUUID
+	ClaimedAt      sql.NullTime
+	ResolvedAt     sql.NullTime
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+}
+
+type User struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	IsChirpyRed     sql.NullBool
+	Username        sql.NullString
+	DisplayName     string
+	Bio             string
+	EmailVerifiedAt sql.NullTime
+	TotpSecret      sql.NullString
+	TotpEnabledAt   sql.NullTime
+	TotpLastStep    int64
+	SuspendedUntil  sql.NullTime
+	Role            string
+	ShadowBanned    bool
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
 }
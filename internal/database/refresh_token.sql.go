@@ -7,12 +7,79 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const consumeRefreshToken = `-- name: ConsumeRefreshToken :execrows
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW()
+`
+
+func (q *Queries) ConsumeRefreshToken(ctx context.Context, token string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, consumeRefreshToken, token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserSessions = `-- name: GetUserSessions :many
+SELECT
+    family_id,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id)::timestamp AS signed_in_at,
+    last_used_at,
+    expires_at,
+    user_agent,
+    ip_address
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_used_at DESC
+`
+
+type GetUserSessionsRow struct {
+	FamilyID   uuid.UUID
+	SignedInAt time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	UserAgent  string
+	IpAddress  string
+}
+
+func (q *Queries) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]GetUserSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserSessionsRow
+	for rows.Next() {
+		var i GetUserSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.SignedInAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.UserAgent,
+			&i.IpAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const lookUpRefreshToken = `-- name: LookUpRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = $1
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip_address, last_used_at FROM refresh_tokens WHERE token = $1
 `
 
 func (q *Queries) LookUpRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -25,29 +92,45 @@ func (q *Queries) LookUpRefreshToken(ctx context.Context, token string) (Refresh
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
 const registerRefreshToken = `-- name: RegisterRefreshToken :one
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id, user_agent, ip_address, last_used_at)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    NOW() + INTERVAL '60 days'
+    NOW() + INTERVAL '60 days',
+    gen_random_uuid(),
+    $3,
+    $4,
+    NOW()
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip_address, last_used_at
 `
 
 type RegisterRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	Token     string
+	UserID    uuid.UUID
+	UserAgent string
+	IpAddress string
 }
 
 func (q *Queries) RegisterRefreshToken(ctx context.Context, arg RegisterRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, registerRefreshToken, arg.Token, arg.UserID)
+	row := q.db.QueryRowContext(ctx, registerRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -56,6 +139,11 @@ func (q *Queries) RegisterRefreshToken(ctx context.Context, arg RegisterRefreshT
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -68,3 +156,93 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeUserSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id, parent_token, user_agent, ip_address, last_used_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    NOW() + INTERVAL '60 days',
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip_address, last_used_at
+`
+
+type RotateRefreshTokenParams struct {
+	Token       string
+	UserID      uuid.UUID
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	UserAgent   string
+	IpAddress   string
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.FamilyID,
+		arg.ParentToken,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
@@ -13,25 +13,38 @@ import (
 	"github.com/google/uuid"
 )
 
+const countAdmins = `-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin'
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_until, role, shadow_banned
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Username)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -40,6 +53,16 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedUntil,
+		&i.Role,
+		&i.ShadowBanned,
 	)
 	return i, err
 }
@@ -53,16 +76,57 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, email, is_chirpy_red, email_verified_at, username,
+    role, suspended_until, shadow_banned FROM users WHERE id = $1
+`
+
+type GetUserRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	IsChirpyRed     sql.NullBool
+	EmailVerifiedAt sql.NullTime
+	Username        sql.NullString
+	Role            string
+	SuspendedUntil  sql.NullTime
+	ShadowBanned    bool
+}
+
+func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (GetUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i GetUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.Username,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.ShadowBanned,
+	)
+	return i, err
+}
+
 const getUserFromEmail = `-- name: GetUserFromEmail :one
-SELECT id, created_at, updated_at, email, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, is_chirpy_red, username, email_verified_at, totp_enabled_at,
+    suspended_until FROM users WHERE email = $1
 `
 
 type GetUserFromEmailRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Email       string
-	IsChirpyRed sql.NullBool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	IsChirpyRed     sql.NullBool
+	Username        sql.NullString
+	EmailVerifiedAt sql.NullTime
+	TotpEnabledAt   sql.NullTime
+	SuspendedUntil  sql.NullTime
 }
 
 func (q *Queries) GetUserFromEmail(ctx context.Context, email string) (GetUserFromEmailRow, error) {
@@ -74,14 +138,114 @@ func (q *Queries) GetUserFromEmail(ctx context.Context, email string) (GetUserFr
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.EmailVerifiedAt,
+		&i.TotpEnabledAt,
+		&i.SuspendedUntil,
 	)
 	return i, err
 }
 
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT users.id, users.username, users.display_name, users.bio, users.created_at, users.is_chirpy_red,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.deleted_at IS NULL) AS chirp_count
+FROM users
+WHERE LOWER(users.username) = LOWER($1)
+`
+
+type GetUserProfileRow struct {
+	ID          uuid.UUID
+	Username    sql.NullString
+	DisplayName string
+	Bio         string
+	CreatedAt   time.Time
+	IsChirpyRed sql.NullBool
+	ChirpCount  int64
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, username string) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, username)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.IsChirpyRed,
+		&i.ChirpCount,
+	)
+	return i, err
+}
+
+const getUserRole = `-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserRole(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserRole, id)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2
+`
+
+type SetUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Role, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserRoleByEmail = `-- name: SetUserRoleByEmail :execrows
+UPDATE users SET role = $1, updated_at = NOW() WHERE email = $2
+`
+
+type SetUserRoleByEmailParams struct {
+	Role  string
+	Email string
+}
+
+func (q *Queries) SetUserRoleByEmail(ctx context.Context, arg SetUserRoleByEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRoleByEmail, arg.Role, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUserEmailPassword = `-- name: UpdateUserEmailPassword :exec
 UPDATE users 
-SET email = COALESCE(NULLIF($1, email), email),
-    hashed_password = COALESCE(NULLIF($2, hashed_password), hashed_password)
+SET email = COALESCE(NULLIF($1, ''), email),
+    hashed_password = COALESCE(NULLIF($2, ''), hashed_password),
+    email_verified_at = CASE WHEN NULLIF($1, '') IS NULL OR $1 = email THEN email_verified_at END
 WHERE id = $3
 RETURNING id, email
 `
@@ -96,3 +260,63 @@ func (q *Queries) UpdateUserEmailPassword(ctx context.Context, arg UpdateUserEma
 	_, err := q.db.ExecContext(ctx, updateUserEmailPassword, arg.Email, arg.HashedPassword, arg.ID)
 	return err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = $1, updated_at = NOW() WHERE id = $2
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET username = COALESCE($1, username),
+    display_name = COALESCE($2, display_name),
+    bio = COALESCE($3, bio),
+    updated_at = NOW()
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_until, role, shadow_banned
+`
+
+type UpdateUserProfileParams struct {
+	Username    sql.NullString
+	DisplayName sql.NullString
+	Bio         sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Username,
+		arg.DisplayName,
+		arg.Bio,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedUntil,
+		&i.Role,
+		&i.ShadowBanned,
+	)
+	return i, err
+}
@@ -0,0 +1,93 @@
+// Package lockout throttles repeated failures, such as wrong passwords, with
+// exponential backoff that ends in a temporary lockout.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Policy lets FreeAttempts failures through, then makes each key wait
+// BaseDelay after its next failure, doubling every time up to MaxDelay. A key
+// at MaxDelay is locked out. Failures older than Window no longer count.
+type Policy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+// Delay is how long a key with the given number of failures has to wait
+// after its last one. PostgresStore computes the same value in SQL.
+func (p Policy) Delay(failures int) time.Duration {
+	extra := failures - p.FreeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := 1; i < extra; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(d, p.MaxDelay)
+}
+
+// allows reports whether a key with failures counted, the last at last, may
+// make another attempt at now.
+func (p Policy) allows(failures int, last, now time.Time) bool {
+	return now.Sub(last) > p.Window || !now.Before(last.Add(p.Delay(failures)))
+}
+
+// Store keeps attempt counts per key.
+type Store interface {
+	// Acquire counts an attempt for key unless the policy says it must
+	// still wait, checking and counting in one atomic step so concurrent
+	// attempts cannot all slip through. Counts last recorded more than
+	// policy.Window before now are forgotten. It returns the count and the
+	// time of the last counted attempt, which for a refused attempt is what
+	// the wait is measured from.
+	Acquire(ctx context.Context, key string, now time.Time, policy Policy) (failures int, last time.Time, allowed bool, err error)
+	// Release takes back one counted attempt.
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter counts every attempt as a failure up front, before the caller
+// knows the outcome, so a burst of parallel guesses is throttled as surely
+// as a sequential one. Callers then Reset or Release on success.
+type Limiter struct {
+	Store Store
+	Policy
+}
+
+// Acquire counts an attempt for key. If the key is still waiting out an
+// earlier failure nothing is counted and the remaining wait is returned.
+func (l *Limiter) Acquire(ctx context.Context, key string, now time.Time) (failures int, wait time.Duration, err error) {
+	failures, last, allowed, err := l.Store.Acquire(ctx, key, now, l.Policy)
+	if err != nil {
+		return 0, 0, err
+	}
+	if allowed {
+		return failures, 0, nil
+	}
+	wait = last.Add(l.Delay(failures)).Sub(now)
+	return failures, max(wait, time.Second), nil
+}
+
+// LockedOut reports whether failures is the count that first pushes a key
+// to MaxDelay, so callers can send a single notice per lockout.
+func (l *Limiter) LockedOut(failures int) bool {
+	return l.Delay(failures) == l.MaxDelay && l.Delay(failures-1) < l.MaxDelay
+}
+
+// Release takes back an attempt that turned out not to be a failure.
+func (l *Limiter) Release(ctx context.Context, key string) error {
+	return l.Store.Release(ctx, key)
+}
+
+// Reset forgets every failure for key.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.Store.Reset(ctx, key)
+}
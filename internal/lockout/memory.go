@@ -0,0 +1,74 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxMemoryEntries bounds the memory store; once reached, entries that fell
+// out of their window are dropped on the next write.
+const maxMemoryEntries = 100000
+
+type entry struct {
+	failures int
+	last     time.Time
+}
+
+// MemoryStore keeps counts in process. It suits a single instance; every
+// instance behind a load balancer would otherwise count separately.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]entry{}}
+}
+
+func (s *MemoryStore) Acquire(ctx context.Context, key string, now time.Time, policy Policy) (int, time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	windowStart := now.Add(-policy.Window)
+	if len(s.entries) >= maxMemoryEntries {
+		for k, e := range s.entries {
+			if e.last.Before(windowStart) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	e := s.entries[key]
+	if e.last.Before(windowStart) {
+		e.failures = 0
+	}
+	if e.failures > 0 && !policy.allows(e.failures, e.last, now) {
+		return e.failures, e.last, false, nil
+	}
+	e.failures++
+	e.last = now
+	s.entries[key] = e
+	return e.failures, e.last, true, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	e.failures--
+	if e.failures <= 0 {
+		delete(s.entries, key)
+		return nil
+	}
+	s.entries[key] = e
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,132 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     10 * time.Second,
+	Window:       time.Hour,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 6, want: 4 * time.Second},
+		{failures: 7, want: 8 * time.Second},
+		{failures: 8, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		got := testPolicy.Delay(tt.failures)
+		if got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockedOut(t *testing.T) {
+	l := &Limiter{Store: NewMemoryStore(), Policy: testPolicy}
+	for failures := 0; failures <= 10; failures++ {
+		want := failures == 8
+		if got := l.LockedOut(failures); got != want {
+			t.Errorf("LockedOut(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestMemoryStoreAcquire(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		after    time.Duration
+		failures int
+		wait     time.Duration
+	}{
+		{name: "first free attempt", failures: 1},
+		{name: "second free attempt", failures: 2},
+		{name: "last free attempt", failures: 3},
+		{name: "first delayed failure", failures: 4},
+		{name: "refused during delay", after: 500 * time.Millisecond, failures: 4, wait: time.Second},
+		{name: "allowed once delay passes", after: time.Second, failures: 5},
+		{name: "refused during doubled delay", after: 500 * time.Millisecond, failures: 5, wait: 1500 * time.Millisecond},
+		{name: "short wait rounds up to a second", after: time.Second, failures: 5, wait: time.Second},
+		{name: "forgotten after the window", after: 2 * time.Hour, failures: 1},
+	}
+	l := &Limiter{Store: NewMemoryStore(), Policy: testPolicy}
+	now := start
+	for _, tt := range tests {
+		now = now.Add(tt.after)
+		failures, wait, err := l.Acquire(ctx, "key", now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if failures != tt.failures || wait != tt.wait {
+			t.Errorf("%s: Acquire = (%d, %v), want (%d, %v)", tt.name, failures, wait, tt.failures, tt.wait)
+		}
+	}
+}
+
+func TestMemoryStoreReleaseAndReset(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	l := &Limiter{Store: NewMemoryStore(), Policy: testPolicy}
+	for i := 0; i < 3; i++ {
+		l.Acquire(ctx, "key", now)
+	}
+	if err := l.Release(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	failures, _, _ := l.Acquire(ctx, "key", now)
+	if failures != 3 {
+		t.Errorf("after Release, failures = %d, want 3", failures)
+	}
+	if err := l.Reset(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	failures, _, _ = l.Acquire(ctx, "key", now)
+	if failures != 1 {
+		t.Errorf("after Reset, failures = %d, want 1", failures)
+	}
+}
+
+// TestMemoryStoreConcurrentAcquire fires a burst of parallel attempts at one
+// key; only the free attempts plus the first delayed one may get through.
+func TestMemoryStoreConcurrentAcquire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	l := &Limiter{Store: NewMemoryStore(), Policy: testPolicy}
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, wait, err := l.Acquire(ctx, "key", now)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := allowed.Load(), int32(testPolicy.FreeAttempts+1); got != want {
+		t.Errorf("%d attempts allowed, want %d", got, want)
+	}
+}
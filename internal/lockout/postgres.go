@@ -0,0 +1,50 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+)
+
+// PostgresStore shares counts between every instance using the database.
+// Times are stored in UTC since the column has no time zone.
+type PostgresStore struct {
+	Queries *database.Queries
+}
+
+// Acquire relies on a single upsert whose update only happens when the
+// backoff has elapsed; its delay expression mirrors Policy.Delay. No row
+// comes back for a refused attempt, which is then read for its wait.
+func (s PostgresStore) Acquire(ctx context.Context, key string, now time.Time, policy Policy) (int, time.Time, bool, error) {
+	now = now.UTC()
+	row, err := s.Queries.AcquireLoginAttempt(ctx, database.AcquireLoginAttemptParams{
+		Key:          key,
+		Now:          now,
+		WindowStart:  now.Add(-policy.Window),
+		FreeAttempts: int32(policy.FreeAttempts),
+		MaxDelayMs:   float64(policy.MaxDelay.Milliseconds()),
+		BaseDelayMs:  float64(policy.BaseDelay.Milliseconds()),
+	})
+	if err == nil {
+		return int(row.Failures), row.LastFailureAt, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, false, err
+	}
+	attempts, err := s.Queries.GetLoginAttempts(ctx, key)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	return int(attempts.Failures), attempts.LastFailureAt, false, nil
+}
+
+func (s PostgresStore) Release(ctx context.Context, key string) error {
+	return s.Queries.ReleaseLoginAttempt(ctx, key)
+}
+
+func (s PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.Queries.ResetLoginAttempts(ctx, key)
+}
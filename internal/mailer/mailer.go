@@ -0,0 +1,134 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email such as verification links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes every message to the process log instead of sending it.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes every message to its own file in Dir, which is handy for
+// inspecting mail in local and end-to-end tests. Messages carry live tokens,
+// so the files are readable by the owner only.
+type FileMailer struct {
+	Dir string
+}
+
+func (m FileMailer) Send(ctx context.Context, msg Message) error {
+	err := os.MkdirAll(m.Dir, 0o700)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(format(msg, "")), 0o600)
+}
+
+// SMTPMailer sends mail through an SMTP relay using PLAIN auth when a
+// username is set.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var a smtp.Auth
+	if m.Username != "" {
+		host, _, _ := strings.Cut(m.Addr, ":")
+		a = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, a, m.From, []string{msg.To}, []byte(format(msg, m.From)))
+}
+
+// FromEnv picks a mailer from the MAILER variable: "smtp", "file" or "log".
+// Anything else falls back to logging. The file mailer writes to MAILER_DIR,
+// or chirpy-mail in the system temp directory, and refuses a directory inside
+// the working directory because that is served as static files.
+func FromEnv() (Mailer, error) {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return SMTPMailer{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}, nil
+	case "file":
+		dir := os.Getenv("MAILER_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "chirpy-mail")
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		inside, err := isWithin(dir, wd)
+		if err != nil {
+			return nil, err
+		}
+		if inside {
+			return nil, fmt.Errorf("MAILER_DIR %q is inside the served directory %q", dir, wd)
+		}
+		return FileMailer{Dir: dir}, nil
+	default:
+		return LogMailer{}, nil
+	}
+}
+
+// isWithin reports whether path is root or lies beneath it.
+func isWithin(path, root string) (bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return false, nil
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))), nil
+}
+
+func format(msg Message, from string) string {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	return b.String()
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromEnvFileMailerDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	tests := []struct {
+		name    string
+		dir     string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", dir: "", want: filepath.Join(os.TempDir(), "chirpy-mail")},
+		{name: "outside", dir: outside, want: outside},
+		{name: "relative", dir: "mail", wantErr: true},
+		{name: "working directory", dir: ".", wantErr: true},
+		{name: "absolute inside", dir: filepath.Join(wd, "static", "mail"), wantErr: true},
+		{name: "escapes and returns", dir: filepath.Join("..", filepath.Base(wd), "mail"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAILER", "file")
+			t.Setenv("MAILER_DIR", tt.dir)
+			m, err := FromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("FromEnv = %+v, want error", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromEnv: %v", err)
+			}
+			if fm, ok := m.(FileMailer); !ok || fm.Dir != tt.want {
+				t.Errorf("FromEnv = %+v, want FileMailer{Dir: %q}", m, tt.want)
+			}
+		})
+	}
+}
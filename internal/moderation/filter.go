@@ -0,0 +1,229 @@
+// Package moderation screens chirp text against a configurable word list.
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Action is what happens to a chirp containing a listed word.
+type Action string
+
+const (
+	// ActionMask replaces the word with asterisks and lets the chirp through.
+	ActionMask Action = "mask"
+	// ActionReject refuses the chirp.
+	ActionReject Action = "reject"
+	// ActionFlag lets the chirp through unchanged but queues it for review.
+	ActionFlag Action = "flag"
+)
+
+const maskReplacement = "****"
+
+func ParseAction(s string) (Action, error) {
+	switch a := Action(strings.ToLower(strings.TrimSpace(s))); a {
+	case ActionMask, ActionReject, ActionFlag:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown moderation action %q", s)
+}
+
+// Rule is one listed word and its action.
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+// DefaultRules is the list used when no file or database source is set up.
+var DefaultRules = []Rule{
+	{Word: "kerfuffle", Action: ActionMask},
+	{Word: "sharbert", Action: ActionMask},
+	{Word: "fornax", Action: ActionMask},
+}
+
+// Match is a listed word found in a text, with its byte offsets.
+type Match struct {
+	Word   string
+	Action Action
+	Start  int
+	End    int
+}
+
+// Result is the outcome of filtering a text. Text has masked words replaced;
+// Rejected and Flagged say whether any match asked for those actions.
+type Result struct {
+	Text     string
+	Matches  []Match
+	Rejected bool
+	Flagged  bool
+}
+
+// FlaggedWords lists the distinct words that flagged the text, sorted.
+func (r Result) FlaggedWords() []string {
+	seen := map[string]bool{}
+	words := []string{}
+	for _, m := range r.Matches {
+		if m.Action == ActionFlag && !seen[m.Word] {
+			seen[m.Word] = true
+			words = append(words, m.Word)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+// Filter is an immutable compiled word list, safe for concurrent use.
+type Filter struct {
+	rules map[string]Rule
+}
+
+// NewFilter compiles rules. When a word appears twice the strictest action
+// wins: reject, then flag, then mask.
+func NewFilter(rules []Rule) *Filter {
+	f := &Filter{rules: map[string]Rule{}}
+	for _, rule := range rules {
+		key := skeleton(rule.Word)
+		if key == "" {
+			continue
+		}
+		if existing, ok := f.rules[key]; ok && severity(existing.Action) >= severity(rule.Action) {
+			continue
+		}
+		f.rules[key] = rule
+	}
+	return f
+}
+
+func severity(a Action) int {
+	switch a {
+	case ActionReject:
+		return 3
+	case ActionFlag:
+		return 2
+	case ActionMask:
+		return 1
+	}
+	return 0
+}
+
+// Len reports how many distinct words the filter holds.
+func (f *Filter) Len() int {
+	return len(f.rules)
+}
+
+// Apply finds listed words in text. Words are split on Unicode letter and
+// digit boundaries, with the symbols commonly used as letter substitutes
+// ("f0rn@x", "$harbert") kept inside a word, then compared by skeleton so
+// case, accents, compatibility forms and leetspeak all match.
+func (f *Filter) Apply(text string) Result {
+	result := Result{Text: text}
+	var b strings.Builder
+	last := 0
+	for _, tok := range tokenize(text) {
+		rule, start, end, ok := f.lookup(text, tok)
+		if !ok {
+			continue
+		}
+		result.Matches = append(result.Matches, Match{Word: rule.Word, Action: rule.Action, Start: start, End: end})
+		switch rule.Action {
+		case ActionReject:
+			result.Rejected = true
+		case ActionFlag:
+			result.Flagged = true
+		case ActionMask:
+			b.WriteString(text[last:start])
+			b.WriteString(maskReplacement)
+			last = end
+		}
+	}
+	if last > 0 {
+		b.WriteString(text[last:])
+		result.Text = b.String()
+	}
+	return result
+}
+
+// lookup tries the whole token first, then the token with substitute symbols
+// trimmed from its ends, so "kerfuffle!" matches without treating every "!"
+// as an "i".
+func (f *Filter) lookup(text string, tok span) (Rule, int, int, bool) {
+	word := text[tok.start:tok.end]
+	if rule, ok := f.rules[skeleton(word)]; ok {
+		return rule, tok.start, tok.end, true
+	}
+	trimmedLeft := strings.TrimLeftFunc(word, isSubstitute)
+	trimmed := strings.TrimRightFunc(trimmedLeft, isSubstitute)
+	if trimmed == "" || trimmed == word {
+		return Rule{}, 0, 0, false
+	}
+	if rule, ok := f.rules[skeleton(trimmed)]; ok {
+		start := tok.start + len(word) - len(trimmedLeft)
+		return rule, start, start + len(trimmed), true
+	}
+	return Rule{}, 0, 0, false
+}
+
+type span struct {
+	start, end int
+}
+
+func isSubstitute(r rune) bool {
+	_, ok := substitutes[r]
+	return ok && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || isSubstitute(r)
+}
+
+func tokenize(text string) []span {
+	spans := []span{}
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// substitutes folds look-alike characters onto one letter. "l", "1", "!"
+// and "|" all become "i" so that either spelling of a listed word matches.
+var substitutes = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'i', '+': 't', 'l': 'i',
+}
+
+// skeleton reduces a word to its comparison form: NFKD-decomposed with
+// combining marks dropped, lowercased, and with substitutes folded.
+func skeleton(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if sub, ok := substitutes[r]; ok {
+			r = sub
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,156 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "fornax", want: "fornax"},
+		{word: "FORNAX", want: "fornax"},
+		{word: "f0rn@x", want: "fornax"},
+		{word: "$harbert", want: "sharbert"},
+		{word: "fórnäx", want: "fornax"},
+		{word: "ｆｏｒｎａｘ", want: "fornax"},
+		{word: "ﬁne", want: "fine"},
+		{word: "kerfuffle", want: "kerfuffie"},
+		{word: "kerfuff1e", want: "kerfuffie"},
+		{word: "k|erfuff!e", want: "kierfuffie"},
+		{word: "for-nax", want: "fornax"},
+		{word: "", want: ""},
+		{word: "...", want: ""},
+	}
+	for _, tt := range tests {
+		if got := skeleton(tt.word); got != tt.want {
+			t.Errorf("skeleton(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	filter := NewFilter([]Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "fornax", Action: ActionMask},
+		{Word: "zorblax", Action: ActionReject},
+		{Word: "grumble", Action: ActionFlag},
+	})
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		rejected bool
+		flagged  bool
+		matches  int
+	}{
+		{name: "clean", text: "hello there", want: "hello there"},
+		{name: "mask", text: "what a kerfuffle today", want: "what a **** today", matches: 1},
+		{name: "mask case", text: "KERFUFFLE", want: "****", matches: 1},
+		{name: "mask leetspeak", text: "f0rn@x and kerfuff1e", want: "**** and ****", matches: 2},
+		{name: "mask accents", text: "Fórnax!", want: "****!", matches: 1},
+		{name: "mask fullwidth", text: "ｆｏｒｎａｘ rocks", want: "**** rocks", matches: 1},
+		{name: "punctuation kept", text: "(kerfuffle), kerfuffle!", want: "(****), ****!", matches: 2},
+		{name: "longer word not matched", text: "kerfuffles fornaxes", want: "kerfuffles fornaxes"},
+		{name: "reject", text: "you z0rblax", want: "you z0rblax", rejected: true, matches: 1},
+		{name: "flag", text: "grumble grumble", want: "grumble grumble", flagged: true, matches: 2},
+		{name: "all actions", text: "kerfuffle grumble zorblax", want: "**** grumble zorblax", rejected: true, flagged: true, matches: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := filter.Apply(tt.text)
+			if result.Text != tt.want {
+				t.Errorf("Text = %q, want %q", result.Text, tt.want)
+			}
+			if result.Rejected != tt.rejected || result.Flagged != tt.flagged {
+				t.Errorf("Rejected, Flagged = %v, %v, want %v, %v", result.Rejected, result.Flagged, tt.rejected, tt.flagged)
+			}
+			if len(result.Matches) != tt.matches {
+				t.Errorf("%d matches, want %d: %+v", len(result.Matches), tt.matches, result.Matches)
+			}
+			for _, m := range result.Matches {
+				if skeleton(tt.text[m.Start:m.End]) != skeleton(m.Word) {
+					t.Errorf("match %+v covers %q", m, tt.text[m.Start:m.End])
+				}
+			}
+		})
+	}
+}
+
+func TestNewFilterKeepsStrictestAction(t *testing.T) {
+	filter := NewFilter([]Rule{
+		{Word: "fornax", Action: ActionMask},
+		{Word: "F0RNAX", Action: ActionReject},
+		{Word: "fornax", Action: ActionFlag},
+		{Word: "--", Action: ActionReject},
+	})
+	if filter.Len() != 1 {
+		t.Errorf("Len = %d, want 1", filter.Len())
+	}
+	if result := filter.Apply("fornax"); !result.Rejected || result.Flagged {
+		t.Errorf("Apply(fornax) = %+v, want rejected only", result)
+	}
+}
+
+func TestFlaggedWords(t *testing.T) {
+	filter := NewFilter([]Rule{
+		{Word: "grumble", Action: ActionFlag},
+		{Word: "mutter", Action: ActionFlag},
+		{Word: "fornax", Action: ActionMask},
+	})
+	got := filter.Apply("mutter grumble fornax mutter").FlaggedWords()
+	want := []string{"grumble", "mutter"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlaggedWords = %v, want %v", got, want)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name:    "words and actions",
+			content: "# banned words\n\nkerfuffle\nsharbert mask\nfornax REJECT\n  grumble flag  \n",
+			want: []Rule{
+				{Word: "kerfuffle", Action: ActionMask},
+				{Word: "sharbert", Action: ActionMask},
+				{Word: "fornax", Action: ActionReject},
+				{Word: "grumble", Action: ActionFlag},
+			},
+		},
+		{name: "empty", content: "# nothing yet\n", want: []Rule{}},
+		{name: "unknown action", content: "fornax ban\n", wantErr: true},
+		{name: "too many fields", content: "fornax mask now\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "words.txt")
+			err := os.WriteFile(path, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rules, err := FileSource{Path: path}.Load(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("Load = %+v, want %+v", rules, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+)
+
+// Source loads the current word list.
+type Source interface {
+	Load(ctx context.Context) ([]Rule, error)
+}
+
+// StaticSource always returns the same rules.
+type StaticSource []Rule
+
+func (s StaticSource) Load(ctx context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+// FileSource reads one word per line, optionally followed by its action:
+//
+//	# comment
+//	kerfuffle
+//	sharbert mask
+//	fornax reject
+//
+// Words without an action are masked.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Load(ctx context.Context) ([]Rule, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rules := []Rule{}
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rule := Rule{Word: fields[0], Action: ActionMask}
+		switch len(fields) {
+		case 1:
+		case 2:
+			rule.Action, err = ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", s.Path, lineNo, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: expected a word and an optional action", s.Path, lineNo)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// DBSource reads the moderation_words table.
+type DBSource struct {
+	Queries *database.Queries
+}
+
+func (s DBSource) Load(ctx context.Context) ([]Rule, error) {
+	rows, err := s.Queries.GetModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(rows))
+	for _, row := range rows {
+		action, err := ParseAction(row.Action)
+		if err != nil {
+			return nil, fmt.Errorf("moderation word %q: %w", row.Word, err)
+		}
+		rules = append(rules, Rule{Word: row.Word, Action: action})
+	}
+	return rules, nil
+}
+
+// Moderator holds the filter currently in use and swaps in a new one on
+// Reload, so requests in flight keep the list they started with.
+type Moderator struct {
+	source  Source
+	current atomic.Pointer[Filter]
+}
+
+// NewModerator loads the initial list from source.
+func NewModerator(ctx context.Context, source Source) (*Moderator, error) {
+	m := &Moderator{source: source}
+	err := m.Reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload replaces the filter with a fresh load from the source. On error
+// the previous filter stays in place.
+func (m *Moderator) Reload(ctx context.Context) error {
+	rules, err := m.source.Load(ctx)
+	if err != nil {
+		return err
+	}
+	m.current.Store(NewFilter(rules))
+	return nil
+}
+
+func (m *Moderator) Filter() *Filter {
+	return m.current.Load()
+}
+
+// FromEnv picks the source named by MODERATION_SOURCE: "file" reads
+// MODERATION_FILE (default moderation.txt), "db" reads moderation_words, and
+// anything else uses DefaultRules.
+func FromEnv(queries *database.Queries) Source {
+	switch os.Getenv("MODERATION_SOURCE") {
+	case "file":
+		path := os.Getenv("MODERATION_FILE")
+		if path == "" {
+			path = "moderation.txt"
+		}
+		return FileSource{Path: path}
+	case "db":
+		return DBSource{Queries: queries}
+	default:
+		return StaticSource(DefaultRules)
+	}
+}
@@ -0,0 +1,286 @@
+// Package oidc is a minimal OpenID Connect relying party: discovery, the
+// authorization code flow with PKCE, and ID token verification against the
+// provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID Connect issuer. Discovery and the key set are
+// fetched lazily and cached, so the server can start while the identity
+// provider is unreachable.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *metadata
+	keys map[string]interface{}
+}
+
+// Identity is what Chirpy needs from a verified ID token.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+func NewProvider(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// FromEnv configures a provider from OIDC_ISSUER, OIDC_CLIENT_ID,
+// OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL and OIDC_SCOPES. It returns nil when
+// no issuer is set, which disables OIDC login.
+func FromEnv(defaultRedirectURL string) *Provider {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = defaultRedirectURL
+	}
+	return NewProvider(Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  redirectURL,
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+	})
+}
+
+// RandomString returns a URL-safe random value for state, nonce and PKCE
+// verifiers.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// S256Challenge derives the PKCE code challenge for a verifier (RFC 7636).
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	meta := &metadata{}
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", meta)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery returned issuer %q, expected %q", meta.Issuer, p.config.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("incomplete discovery document from %s", issuer)
+	}
+	p.meta = meta
+	return meta, nil
+}
+
+// AuthCodeURL is where the user is sent to sign in with the provider.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {S256Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified identity
+// from the ID token in the response.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Identity{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	tokens := struct {
+		IDToken string `json:"id_token"`
+	}{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens)
+	if err != nil {
+		return Identity{}, err
+	}
+	if tokens.IDToken == "" {
+		return Identity{}, fmt.Errorf("token endpoint returned no id_token")
+	}
+	return p.verifyIDToken(ctx, meta, tokens.IDToken, nonce)
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, meta *metadata, rawIDToken, nonce string) (Identity, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.verificationKey(ctx, meta, kid)
+		if err != nil {
+			return nil, err
+		}
+		if !algMatchesKey(token.Method, key) {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	if !claims.VerifyIssuer(meta.Issuer, true) {
+		return Identity{}, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return Identity{}, fmt.Errorf("ID token was not issued for this client")
+	}
+	if claims.Nonce != nonce {
+		return Identity{}, fmt.Errorf("ID token nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("ID token has no subject")
+	}
+	// Some providers send email_verified as a string.
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+	return Identity{
+		Issuer:        meta.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+	}, nil
+}
+
+// verificationKey returns the provider key with the given kid, refetching
+// the key set once when the kid is unknown in case the provider rotated.
+func (p *Provider) verificationKey(ctx context.Context, meta *metadata, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if p.keys == nil || attempt == 1 {
+			keys, err := p.fetchKeys(ctx, meta.JWKSURI)
+			if err != nil {
+				return nil, err
+			}
+			p.keys = keys
+		}
+		if key, ok := p.keys[kid]; ok {
+			return key, nil
+		}
+		if kid == "" && len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
@@ -0,0 +1,210 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testClientID = "chirpy-test"
+	testCode     = "auth-code"
+)
+
+// stubIssuer is an in-process OpenID provider. It remembers the PKCE
+// challenge from the authorization request and only redeems the code for a
+// matching verifier, then returns whatever ID token claims the test set.
+type stubIssuer struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	signer    *rsa.PrivateKey
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubIssuer{key: key, signer: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.server.URL,
+			"authorization_endpoint": s.server.URL + "/authorize",
+			"token_endpoint":         s.server.URL + "/token",
+			"jwks_uri":               s.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != testCode || S256Challenge(r.Form.Get("code_verifier")) != s.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, s.claims)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(s.signer)
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubIssuer) defaultClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            s.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"nonce":          nonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestExchange(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		modify       func(s *stubIssuer, claims jwt.MapClaims)
+		verifier     string
+		wantErr      bool
+		wantVerified bool
+	}{
+		{name: "valid", wantVerified: true},
+		{
+			name:         "email_verified as string",
+			modify:       func(s *stubIssuer, claims jwt.MapClaims) { claims["email_verified"] = "true" },
+			wantVerified: true,
+		},
+		{
+			name:   "email not verified",
+			modify: func(s *stubIssuer, claims jwt.MapClaims) { claims["email_verified"] = false },
+		},
+		{name: "wrong PKCE verifier", verifier: "not-the-verifier", wantErr: true},
+		{
+			name:    "wrong nonce",
+			modify:  func(s *stubIssuer, claims jwt.MapClaims) { claims["nonce"] = "replayed" },
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			modify:  func(s *stubIssuer, claims jwt.MapClaims) { claims["aud"] = "someone-else" },
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			modify:  func(s *stubIssuer, claims jwt.MapClaims) { claims["iss"] = "https://evil.example" },
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			modify:  func(s *stubIssuer, claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr: true,
+		},
+		{
+			name:    "signed by unknown key",
+			modify:  func(s *stubIssuer, claims jwt.MapClaims) { s.signer = otherKey },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			issuer := newStubIssuer(t)
+			provider := NewProvider(Config{
+				Issuer:      issuer.server.URL,
+				ClientID:    testClientID,
+				RedirectURL: "http://localhost/callback",
+			})
+			verifier, err := RandomString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce, err := RandomString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			authURL, err := provider.AuthCodeURL(ctx, "state", nonce, verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := url.Parse(authURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := parsed.Query()
+			if query.Get("code_challenge_method") != "S256" || query.Get("nonce") != nonce {
+				t.Fatalf("authorization URL missing PKCE or nonce: %s", authURL)
+			}
+			issuer.challenge = query.Get("code_challenge")
+			issuer.claims = issuer.defaultClaims(nonce)
+			if tt.modify != nil {
+				tt.modify(issuer, issuer.claims)
+			}
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+
+			identity, err := provider.Exchange(ctx, testCode, verifier, nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Exchange succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Issuer != issuer.server.URL || identity.Subject != "user-123" || identity.Email != "user@example.com" {
+				t.Errorf("identity = %+v", identity)
+			}
+			if identity.EmailVerified != tt.wantVerified {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestS256Challenge(t *testing.T) {
+	// RFC 7636 appendix B.
+	got := S256Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if got != want {
+		t.Errorf("S256Challenge = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+// optionalViewer identifies the caller on public endpoints. A missing or
+// invalid token just means an anonymous viewer.
+func (cfg *apiConfig) optionalViewer(r *http.Request) uuid.NullUUID {
+	userID, err := cfg.authorizedUser(r, scopeChirpsRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// decorateChirps embeds the originals of rechirps and quotes, then fills in
+// mentions and like counts for the whole batch with one query each, plus
+// liked_by_me when the viewer is known.
+func (cfg *apiConfig) decorateChirps(ctx context.Context, chirps []*Chirp, viewer uuid.NullUUID) error {
+	var available []*Chirp
+	for _, chirp := range chirps {
+		if !chirp.Unavailable {
+			available = append(available, chirp)
+		}
+	}
+	chirps = available
+	if len(chirps) == 0 {
+		return nil
+	}
+	originals, err := cfg.embedOriginals(ctx, chirps, viewer)
+	if err != nil {
+		return err
+	}
+	chirps = append(chirps, originals...)
+	err = cfg.attachMentions(ctx, chirps)
+	if err != nil {
+		return err
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	summaries, err := cfg.database.GetChirpLikeSummaries(ctx, database.GetChirpLikeSummariesParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	bySummary := make(map[uuid.UUID]database.GetChirpLikeSummariesRow, len(summaries))
+	for _, summary := range summaries {
+		bySummary[summary.ChirpID] = summary
+	}
+	for _, chirp := range chirps {
+		summary := bySummary[chirp.ID]
+		chirp.LikeCount = summary.LikeCount
+		if viewer.Valid {
+			likedByMe := summary.LikedByMe
+			chirp.LikedByMe = &likedByMe
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	chirp, err := cfg.resolveOriginal(ctx, id)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	err = cfg.database.LikeChirp(ctx, database.LikeChirpParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to like Chirp")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	chirp, err := cfg.resolveOriginal(ctx, id)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	err = cfg.database.UnlikeChirp(ctx, database.UnlikeChirpParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to unlike Chirp")
+		return
+	}
+	w.WriteHeader(204)
+}
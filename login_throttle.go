@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/lockout"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/mailer"
+)
+
+// loginThrottle limits password and second-factor guesses per account and
+// per client IP. The IP limit is looser because many users can share one
+// address behind a NAT.
+type loginThrottle struct {
+	accounts *lockout.Limiter
+	ips      *lockout.Limiter
+}
+
+func newLoginThrottle(store lockout.Store) loginThrottle {
+	return loginThrottle{
+		accounts: &lockout.Limiter{
+			Store: store,
+			Policy: lockout.Policy{
+				FreeAttempts: 5,
+				BaseDelay:    time.Second,
+				MaxDelay:     15 * time.Minute,
+				Window:       24 * time.Hour,
+			},
+		},
+		ips: &lockout.Limiter{
+			Store: store,
+			Policy: lockout.Policy{
+				FreeAttempts: 20,
+				BaseDelay:    time.Second,
+				MaxDelay:     15 * time.Minute,
+				Window:       time.Hour,
+			},
+		},
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginAttempt is one guess at a password or second-factor code. It is
+// counted as a failure against the account and the client IP before the
+// guess is checked, and taken back if it turns out to be right.
+type loginAttempt struct {
+	accountKey      string
+	ipKey           string
+	accountFailures int
+}
+
+// beginLoginAttempt counts an attempt, or returns how long the caller must
+// wait if the account or IP is still backing off. Store errors are logged
+// and let the attempt through, as a failing store should not lock every
+// user out.
+func (cfg *apiConfig) beginLoginAttempt(r *http.Request, accountKey string) (loginAttempt, time.Duration) {
+	ctx := r.Context()
+	now := time.Now()
+	attempt := loginAttempt{
+		accountKey: accountKey,
+		ipKey:      "ip:" + cfg.clientIP(r),
+	}
+	failures, wait, err := cfg.loginThrottle.accounts.Acquire(ctx, attempt.accountKey, now)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+	if wait > 0 {
+		return loginAttempt{}, wait
+	}
+	attempt.accountFailures = failures
+	_, wait, err = cfg.loginThrottle.ips.Acquire(ctx, attempt.ipKey, now)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+	if wait > 0 {
+		err = cfg.loginThrottle.accounts.Release(ctx, attempt.accountKey)
+		if err != nil {
+			fmt.Printf("Error %v", err)
+		}
+		return loginAttempt{}, wait
+	}
+	return attempt, 0
+}
+
+// loginFailed leaves the attempt counted. When notifyEmail is set and this
+// failure locks the account, its owner is told by email.
+func (cfg *apiConfig) loginFailed(r *http.Request, attempt loginAttempt, notifyEmail string) {
+	if notifyEmail == "" || !cfg.loginThrottle.accounts.LockedOut(attempt.accountFailures) {
+		return
+	}
+	err := cfg.sendLockoutNotice(r.Context(), notifyEmail, cfg.clientIP(r))
+	if err != nil {
+		fmt.Printf("Error sending lockout notice: %v \n", err)
+	}
+}
+
+// loginSucceeded clears the account's failures and gives the IP its attempt
+// back, so busy shared addresses are not throttled for correct logins.
+func (cfg *apiConfig) loginSucceeded(ctx context.Context, attempt loginAttempt) {
+	err := cfg.loginThrottle.accounts.Reset(ctx, attempt.accountKey)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+	err = cfg.loginThrottle.ips.Release(ctx, attempt.ipKey)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+}
+
+func (cfg *apiConfig) sendLockoutNotice(ctx context.Context, email, ip string) error {
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Too many failed sign-ins to your Chirpy account",
+		Body: fmt.Sprintf("There have been repeated failed attempts to sign in to your Chirpy account, "+
+			"most recently from %s, so sign-in is paused for %v.\n\n"+
+			"If this wasn't you, consider resetting your password through POST /api/password/forgot.\n",
+			ip, cfg.loginThrottle.accounts.MaxDelay),
+	})
+}
+
+func respondWithTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, 429, "Too many failed attempts, try again later")
+}
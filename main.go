@@ -1,47 +1,118 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
-	"strings"
 	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
 	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
 	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/lockout"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/mailer"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/moderation"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/oidc"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	database       *database.Queries
-	jwtKey         string
-	polkaKey       string
+	fileserverHits  atomic.Int32
+	db              *sql.DB
+	database        *database.Queries
+	purposeSecret   string
+	jwtKeys         *auth.KeySet
+	oidcProvider    *oidc.Provider
+	loginThrottle   loginThrottle
+	polkaKey        string
+	platform        string
+	moderator       *moderation.Moderator
+	trendingWindow  time.Duration
+	mailer          mailer.Mailer
+	baseURL         string
+	requireVerified bool
+	trustedProxies  []netip.Prefix
 }
 
+const maxChirpLength = 140
+
+var (
+	errChirpTooLong  = errors.New("Chirp is too long")
+	errChirpRejected = errors.New("Chirp contains prohibited content")
+)
+
 type User struct {
 	ID           uuid.UUID `json:"id"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Username     string    `json:"username,omitempty"`
+	Verified     bool      `json:"email_verified"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 	ChirpyRed    bool      `json:"is_chirpy_red"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID      `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Body        string         `json:"body"`
+	UserID      uuid.UUID      `json:"user_id"`
+	InReplyTo   *uuid.UUID     `json:"in_reply_to,omitempty"`
+	RechirpOf   *uuid.UUID     `json:"rechirp_of,omitempty"`
+	QuoteOf     *uuid.UUID     `json:"quote_of,omitempty"`
+	Original    *Chirp         `json:"original,omitempty"`
+	Mentions    []ChirpMention `json:"mentions,omitempty"`
+	Deleted     bool           `json:"deleted,omitempty"`
+	Unavailable bool           `json:"unavailable,omitempty"`
+	LikeCount   int64          `json:"like_count"`
+	LikedByMe   *bool          `json:"liked_by_me,omitempty"`
+}
+
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		Deleted:   dbChirp.DeletedAt.Valid,
+	}
+	if dbChirp.InReplyTo.Valid {
+		chirp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	if dbChirp.RechirpOf.Valid {
+		chirp.RechirpOf = &dbChirp.RechirpOf.UUID
+	}
+	if dbChirp.QuoteOf.Valid {
+		chirp.QuoteOf = &dbChirp.QuoteOf.UUID
+	}
+	return chirp
+}
+
+// unavailableChirp stands in for a chirp hidden from the viewer. It keeps only
+// what places it in a thread, so embeds and threads still show where it was.
+func unavailableChirp(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:          dbChirp.ID,
+		CreatedAt:   dbChirp.CreatedAt,
+		UpdatedAt:   dbChirp.CreatedAt,
+		Unavailable: true,
+	}
+	if dbChirp.InReplyTo.Valid {
+		chirp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	return chirp
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -57,6 +128,13 @@ func healthzHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
+// jwksHandler publishes the public keys access tokens can be verified with,
+// so other services can check Chirpy tokens without sharing a secret.
+func (cfg *apiConfig) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, 200, cfg.jwtKeys.JWKS())
+}
+
 func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(200)
@@ -64,11 +142,10 @@ func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(val))
 }
 
+// resetHandler wipes every user. Besides an admin token it needs the server
+// to have been started with PLATFORM=dev, so it can never run in production.
 func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
-	godotenv.Load()
-	platform := os.Getenv("PLATFORM")
-
-	if platform == "dev" {
+	if cfg.platform == "dev" {
 		err := cfg.database.DeleteAllUsers(r.Context())
 		if err != nil {
 			respondWithError(w, 400, "Unable to delete user table")
@@ -111,31 +188,42 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(file)
 }
 
-func (cfg *apiConfig) validateChirpHandler(originalChirp string) string {
-	fullSentence := strings.ToLower(originalChirp)
-	sep := " "
-	ogSplitSentence := strings.Split(originalChirp, sep)
-	splitSentence := strings.Split(fullSentence, sep)
-	for index, word := range splitSentence {
-		switch word {
-		case "kerfuffle":
-			ogSplitSentence[index] = "****"
-		case "sharbert":
-			ogSplitSentence[index] = "****"
-		case "fornax":
-			ogSplitSentence[index] = "****"
-		default:
-			continue
-		}
+// authenticatedUser returns the user behind the request's bearer JWT.
+func (cfg *apiConfig) authenticatedUser(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.UUID{}, err
 	}
-	cleanedSentence := strings.Join(ogSplitSentence, sep)
-	return cleanedSentence
+	return auth.ValidateJWT(token, cfg.jwtKeys)
+}
+
+// prepareChirpBody applies the length limit and content filter shared by
+// every endpoint that writes a chirp body. It returns the body with masked
+// words replaced and the listed words that flag the chirp for review.
+func (cfg *apiConfig) prepareChirpBody(body string) (string, []string, error) {
+	if utf8.RuneCountInString(body) > maxChirpLength {
+		return "", nil, errChirpTooLong
+	}
+	result := cfg.moderator.Filter().Apply(body)
+	if result.Rejected {
+		return "", nil, errChirpRejected
+	}
+	return result.Text, result.FlaggedWords(), nil
+}
+
+func respondWithChirpBodyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errChirpRejected) {
+		respondWithError(w, 422, err.Error())
+		return
+	}
+	respondWithError(w, 400, err.Error())
 }
 
 func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	type userCreation struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string  `json:"email"`
+		Password string  `json:"password"`
+		Username *string `json:"username"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -145,6 +233,25 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 400, "Unable to process request")
 		return
 	}
+	err = validateEmail(email.Email)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	err = validateProfile(email.Username, nil, nil)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	username := ""
+	if email.Username != nil {
+		username = *email.Username
+	}
+	err = auth.CheckPasswordStrength(email.Password, email.Email, username)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
 	hashedPass, err := auth.HashPassword(email.Password)
 	if err != nil {
 		respondWithError(w, 400, "Unable to create user, faulty password")
@@ -154,9 +261,14 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	params := database.CreateUserParams{
 		Email:          email.Email,
 		HashedPassword: hashedPass,
+		Username:       nullString(email.Username),
 	}
 	ctx := r.Context()
 	dbUser, err := cfg.database.CreateUser(ctx, params)
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Email or username is already taken")
+		return
+	}
 	if err != nil {
 		respondWithError(w, 400, "Unable to create user")
 		return
@@ -166,22 +278,29 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: dbUser.CreatedAt,
 		UpdatedAt: dbUser.UpdatedAt,
 		Email:     dbUser.Email,
+		Username:  dbUser.Username.String,
+	}
+	err = cfg.sendVerificationEmail(ctx, dbUser.ID, dbUser.Email)
+	if err != nil {
+		fmt.Printf("Error sending verification email: %v \n", err)
 	}
 	respondWithJSON(w, 201, user)
 }
 
 func (cfg *apiConfig) chirps(w http.ResponseWriter, r *http.Request) {
 	type incomingChirp struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
 	}
-	token, err := auth.GetBearerToken(r.Header)
+	fromUser, err := cfg.authorizedUser(r, scopeChirpsWrite)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithAuthError(w, err)
 		return
 	}
-	fromUser, err := auth.ValidateJWT(token, cfg.jwtKey)
+	err = cfg.ensureEmailVerified(r.Context(), fromUser)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, 403, errEmailNotVerified.Error())
 		return
 	}
 	decoder := json.NewDecoder(r.Body)
@@ -191,99 +310,137 @@ func (cfg *apiConfig) chirps(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 400, "Unable to process Chirp")
 		return
 	}
-	count := utf8.RuneCountInString(newChirp.Body)
-	if count > 140 {
-		code := 400
-		msg := "Chirp is too long"
-		respondWithError(w, code, msg)
+	var flags []string
+	newChirp.Body, flags, err = cfg.prepareChirpBody(newChirp.Body)
+	if err != nil {
+		respondWithChirpBodyError(w, err)
 		return
 	}
-	newChirp.Body = cfg.validateChirpHandler(newChirp.Body)
 	ctx := r.Context()
 	params := database.CreateChirpParams{
 		Body:   newChirp.Body,
 		UserID: fromUser,
 	}
-	dbChirp, err := cfg.database.CreateChirp(ctx, params)
+	if newChirp.InReplyTo != nil {
+		parent, err := cfg.resolveOriginal(ctx, *newChirp.InReplyTo)
+		if err != nil {
+			respondWithError(w, 400, "Unable to reply to Chirp")
+			return
+		}
+		blocked, err := cfg.blockedBetween(ctx, fromUser, parent.UserID)
+		if err != nil {
+			respondWithError(w, 500, "Unable to create Chirp")
+			return
+		}
+		if blocked {
+			respondWithError(w, 403, errBlocked.Error())
+			return
+		}
+		params.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+	if newChirp.QuoteOf != nil {
+		quoted, err := cfg.resolveOriginal(ctx, *newChirp.QuoteOf)
+		if err != nil {
+			respondWithError(w, 400, "Unable to quote Chirp")
+			return
+		}
+		blocked, err := cfg.blockedBetween(ctx, fromUser, quoted.UserID)
+		if err != nil {
+			respondWithError(w, 500, "Unable to create Chirp")
+			return
+		}
+		if blocked {
+			respondWithError(w, 403, errBlocked.Error())
+			return
+		}
+		params.QuoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to create Chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	dbChirp, err := qtx.CreateChirp(ctx, params)
 	if err != nil {
 		fmt.Printf("Error %v", err)
 		respondWithError(w, 400, "Unable to create Chirp")
 		return
 	}
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	err = indexChirpText(ctx, qtx, dbChirp.ID, dbChirp.UserID, dbChirp.Body)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to create Chirp")
+		return
+	}
+	err = flagChirp(ctx, qtx, dbChirp, flags)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to create Chirp")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to create Chirp")
+		return
+	}
+	chirp := chirpFromDB(dbChirp)
+	err = cfg.decorateChirps(ctx, []*Chirp{&chirp}, uuid.NullUUID{UUID: fromUser, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Unable to create Chirp")
+		return
 	}
 	respondWithJSON(w, 201, chirp)
 }
 
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	author := r.URL.Query().Get("author_id")
-	order := r.URL.Query().Get("sort")
-	var dbChirps []database.Chirp
-	var err error
-	if author != "" {
-		userID, err := uuid.Parse(author)
-		if err != nil {
-			respondWithError(w, 400, "Unable to retrieve Chirps")
-		}
-		if order == "desc" {
-			dbChirps, err = cfg.database.GetUserChirpsDesc(ctx, userID)
-			if err != nil {
-				fmt.Printf("Error %v", err)
-				respondWithError(w, 400, "Unable to retrieve Chirps")
-				return
-			}
-		} else {
-			dbChirps, err = cfg.database.GetUserChirps(ctx, userID)
-			if err != nil {
-				fmt.Printf("Error %v", err)
-				respondWithError(w, 400, "Unable to retrieve Chirps")
-				return
-			}
-		}
-		var chirps []Chirp
-		for _, dbChirp := range dbChirps {
-			chirps = append(chirps, Chirp{
-				ID:        dbChirp.ID,
-				CreatedAt: dbChirp.CreatedAt,
-				UpdatedAt: dbChirp.UpdatedAt,
-				Body:      dbChirp.Body,
-				UserID:    dbChirp.UserID,
-			})
-		}
-		respondWithJSON(w, 200, chirps)
+	query := r.URL.Query()
+	page, err := parsePageParams(query)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
 		return
 	}
-	if order == "desc" {
-		dbChirps, err = cfg.database.GetChirpsDesc(ctx)
-		if err != nil {
-			fmt.Printf("Error %v", err)
-			respondWithError(w, 400, "Unable to retrieve Chirps")
-			return
-		}
-	} else {
-		dbChirps, err = cfg.database.GetChirps(ctx)
+	authorID := uuid.NullUUID{}
+	if author := query.Get("author_id"); author != "" {
+		userID, err := uuid.Parse(author)
 		if err != nil {
-			fmt.Printf("Error %v", err)
 			respondWithError(w, 400, "Unable to retrieve Chirps")
 			return
 		}
+		authorID = uuid.NullUUID{UUID: userID, Valid: true}
 	}
-	var chirps []Chirp
-	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
+	var dbChirps []database.Chirp
+	viewer := cfg.optionalViewer(r)
+	if query.Get("sort") == "desc" {
+		dbChirps, err = cfg.database.GetChirpsDesc(ctx, database.GetChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			ViewerID:        viewer,
+			PageSize:        page.fetchSize(),
+		})
+	} else {
+		dbChirps, err = cfg.database.GetChirps(ctx, database.GetChirpsParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			ViewerID:        viewer,
+			PageSize:        page.fetchSize(),
 		})
 	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 400, "Unable to retrieve Chirps")
+		return
+	}
+	chirps, err := cfg.newChirpPage(ctx, dbChirps, page.limit, viewer)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve Chirps")
+		return
+	}
 	respondWithJSON(w, 200, chirps)
 }
 
@@ -296,18 +453,21 @@ func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
 	}
 	fmt.Printf("getChirp parsed ID: %v", id)
 	ctx := r.Context()
-	dbChirp, err := cfg.database.GetChirp(ctx, id)
-	if err != nil {
+	viewer := cfg.optionalViewer(r)
+	row, err := cfg.database.GetChirpForViewer(ctx, database.GetChirpForViewerParams{
+		ViewerID: viewer,
+		ID:       id,
+	})
+	if err != nil || row.Chirp.DeletedAt.Valid || !row.Visible {
 		fmt.Printf("Error %v", err)
 		w.WriteHeader(404)
 		return
 	}
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	chirp := chirpFromDB(row.Chirp)
+	err = cfg.decorateChirps(ctx, []*Chirp{&chirp}, viewer)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve Chirp")
+		return
 	}
 	respondWithJSON(w, 200, chirp)
 }
@@ -327,9 +487,15 @@ func (cfg *apiConfig) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	fmt.Printf("received password: %v \n received email: %v \n", receivedLogin.Password, receivedLogin.Email)
+	attempt, wait := cfg.beginLoginAttempt(r, accountThrottleKey(receivedLogin.Email))
+	if wait > 0 {
+		respondWithTooManyAttempts(w, wait)
+		return
+	}
 	hashedPass, err := cfg.database.PullUserPassword(ctx, receivedLogin.Email)
 	if err != nil {
 		fmt.Printf("Error %v \n", err)
+		cfg.loginFailed(r, attempt, "")
 		respondWithError(w, 401, "Incorrect email or password")
 		return
 	}
@@ -337,46 +503,97 @@ func (cfg *apiConfig) login(w http.ResponseWriter, r *http.Request) {
 	err = auth.CheckPasswordHash(receivedLogin.Password, hashedPass)
 	if err != nil {
 		fmt.Printf("Error %v \n", err)
+		cfg.loginFailed(r, attempt, receivedLogin.Email)
 		respondWithError(w, 401, "Incorrect email or password")
 		return
 	}
+	cfg.loginSucceeded(ctx, attempt)
 	dbUser, err := cfg.database.GetUserFromEmail(ctx, receivedLogin.Email)
 	if err != nil {
 		respondWithError(w, 500, "Unable to retrieve user details")
 		return
 	}
+	if isSuspended(dbUser.SuspendedUntil) {
+		respondWithSuspended(w, dbUser.SuspendedUntil)
+		return
+	}
+	if auth.NeedsRehash(hashedPass) {
+		cfg.rehashPassword(ctx, dbUser.ID, receivedLogin.Password)
+	}
 	user := User{
 		ID:           dbUser.ID,
 		CreatedAt:    dbUser.CreatedAt,
 		UpdatedAt:    dbUser.UpdatedAt,
 		Email:        dbUser.Email,
+		Username:     dbUser.Username.String,
+		Verified:     dbUser.EmailVerifiedAt.Valid,
 		Token:        "",
 		RefreshToken: "",
 		ChirpyRed:    dbUser.IsChirpyRed.Bool,
 	}
-	user.Token, err = auth.MakeJWT(user.ID, cfg.jwtKey, time.Duration(3600)*time.Second)
+	if dbUser.TotpEnabledAt.Valid {
+		cfg.respondWithTwoFactorChallenge(w, user.ID)
+		return
+	}
+	err = cfg.startSession(r, &user)
 	if err != nil {
 		respondWithError(w, 500, "Unable to create session")
 		return
 	}
+	respondWithJSON(w, 200, user)
+}
+
+// rehashPassword upgrades a stored hash to the current format and
+// parameters once the plaintext is known to be correct. Failure only means
+// the upgrade is retried on the next login.
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashedPass, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		return
+	}
+	err = cfg.database.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		HashedPassword: hashedPass,
+		ID:             userID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+}
+
+// startSession issues the access JWT and a new refresh token for a user who
+// has fully authenticated, recording the device the request came from.
+func (cfg *apiConfig) startSession(r *http.Request, user *User) error {
+	role, err := cfg.database.GetUserRole(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	user.Token, err = auth.MakeJWT(user.ID, role, cfg.jwtKeys, time.Duration(3600)*time.Second)
+	if err != nil {
+		return err
+	}
 	RefTokStruct := database.RegisterRefreshTokenParams{
-		Token:  "",
-		UserID: user.ID,
+		Token:     "",
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IpAddress: cfg.clientIP(r),
 	}
 	RefTokStruct.Token, err = auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, 500, "Unable to create session")
-		return
+		return err
 	}
-	_, err = cfg.database.RegisterRefreshToken(ctx, RefTokStruct)
+	_, err = cfg.database.RegisterRefreshToken(r.Context(), RefTokStruct)
 	if err != nil {
-		respondWithError(w, 500, "Unable to register session")
-		return
+		return err
 	}
 	user.RefreshToken = RefTokStruct.Token
-	respondWithJSON(w, 200, user)
+	return nil
 }
 
+// refresh exchanges a refresh token for a new access JWT and a new refresh
+// token in the same family, revoking the one presented. Presenting a token
+// that was already rotated or revoked means it has leaked, so the whole
+// family is revoked and every holder has to log in again.
 func (cfg *apiConfig) refresh(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	receivedRefreshToken, err := auth.GetBearerToken(r.Header)
@@ -389,24 +606,91 @@ func (cfg *apiConfig) refresh(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 401, "")
 		return
 	}
-	if time.Now().After(refreshToken.ExpiresAt) || refreshToken.RevokedAt.Valid {
+	if refreshToken.RevokedAt.Valid {
+		cfg.revokeTokenFamily(ctx, refreshToken.FamilyID)
+		respondWithError(w, 401, "")
+		return
+	}
+	if time.Now().After(refreshToken.ExpiresAt) {
 		respondWithError(w, 401, "")
 		return
 	}
 	type respondWithNewToken struct {
-		JWT string `json:"token"`
+		JWT          string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	// The user is read afresh so role changes reach the next access token
+	// rather than waiting for a new login, and suspensions take effect.
+	dbUser, err := cfg.database.GetUser(ctx, refreshToken.UserID)
+	if err != nil {
+		respondWithError(w, 401, "")
+		return
+	}
+	if isSuspended(dbUser.SuspendedUntil) {
+		cfg.revokeTokenFamily(ctx, refreshToken.FamilyID)
+		respondWithSuspended(w, dbUser.SuspendedUntil)
+		return
+	}
+	newJWTString, err := auth.MakeJWT(refreshToken.UserID, dbUser.Role, cfg.jwtKeys, time.Duration(3600)*time.Second)
+	if err != nil {
+		respondWithError(w, 401, "")
+		return
+	}
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
 	}
-	newJWTString, err := auth.MakeJWT(refreshToken.UserID, cfg.jwtKey, time.Duration(3600)*time.Second)
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	consumed, err := qtx.ConsumeRefreshToken(ctx, refreshToken.Token)
 	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
+	if consumed == 0 {
+		// Another request rotated this token between the lookup and now.
+		tx.Rollback()
+		cfg.revokeTokenFamily(ctx, refreshToken.FamilyID)
 		respondWithError(w, 401, "")
 		return
 	}
+	_, err = qtx.RotateRefreshToken(ctx, database.RotateRefreshTokenParams{
+		Token:       newRefreshToken,
+		UserID:      refreshToken.UserID,
+		FamilyID:    refreshToken.FamilyID,
+		ParentToken: sql.NullString{String: refreshToken.Token, Valid: true},
+		UserAgent:   r.UserAgent(),
+		IpAddress:   cfg.clientIP(r),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
 	token := respondWithNewToken{
-		JWT: newJWTString,
+		JWT:          newJWTString,
+		RefreshToken: newRefreshToken,
 	}
 	respondWithJSON(w, 200, token)
 }
 
+func (cfg *apiConfig) revokeTokenFamily(ctx context.Context, familyID uuid.UUID) {
+	err := cfg.database.RevokeRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+}
+
 func (cfg *apiConfig) revoke(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	recToken, err := auth.GetBearerToken(r.Header)
@@ -429,18 +713,16 @@ func (cfg *apiConfig) revoke(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 	type userDataChange struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		Password    string  `json:"password"`
+		Email       string  `json:"email"`
+		Username    *string `json:"username"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
 	}
 	ctx := r.Context()
-	token, err := auth.GetBearerToken(r.Header)
+	userID, err := cfg.authorizedUser(r, scopeProfileWrite)
 	if err != nil {
-		respondWithError(w, 401, "")
-		return
-	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtKey)
-	if err != nil {
-		respondWithError(w, 401, "")
+		respondWithAuthError(w, err)
 		return
 	}
 	receivedData := userDataChange{}
@@ -450,42 +732,107 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 401, "")
 		return
 	}
-	password, err := auth.HashPassword(receivedData.Password)
+	// Personal access tokens may edit the profile but never take over the
+	// account by changing its credentials.
+	if _, err := auth.GetPersonalToken(r.Header); err == nil && (receivedData.Email != "" || receivedData.Password != "") {
+		respondWithError(w, 403, "Email and password can only be changed after logging in")
+		return
+	}
+	if receivedData.Email != "" {
+		err = validateEmail(receivedData.Email)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+	}
+	err = validateProfile(receivedData.Username, receivedData.DisplayName, receivedData.Bio)
 	if err != nil {
-		respondWithError(w, 400, "Unable to change password, faulty password")
+		respondWithError(w, 400, err.Error())
 		return
 	}
+	password := ""
+	if receivedData.Password != "" {
+		current, err := cfg.database.GetUser(ctx, userID)
+		if err != nil {
+			respondWithError(w, 401, "")
+			return
+		}
+		username := current.Username.String
+		if receivedData.Username != nil {
+			username = *receivedData.Username
+		}
+		err = auth.CheckPasswordStrength(receivedData.Password, current.Email, receivedData.Email, username)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		password, err = auth.HashPassword(receivedData.Password)
+		if err != nil {
+			respondWithError(w, 400, "Unable to change password, faulty password")
+			return
+		}
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
 	updatePasswordEmailQuery := database.UpdateUserEmailPasswordParams{
 		Email:          receivedData.Email,
 		HashedPassword: password,
 		ID:             userID,
 	}
-	err = cfg.database.UpdateUserEmailPassword(ctx, updatePasswordEmailQuery)
+	err = qtx.UpdateUserEmailPassword(ctx, updatePasswordEmailQuery)
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Email is already taken")
+		return
+	}
 	if err != nil {
 		respondWithError(w, 401, "")
 		return
 	}
-	type respondStruct struct {
-		Email string `json:"email"`
+	dbUser, err := qtx.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+		Username:    nullString(receivedData.Username),
+		DisplayName: nullString(receivedData.DisplayName),
+		Bio:         nullString(receivedData.Bio),
+		ID:          userID,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Username is already taken")
+		return
 	}
-	response := respondStruct{
-		Email: receivedData.Email,
+	if err != nil {
+		respondWithError(w, 401, "")
+		return
 	}
-	if receivedData.Email == "" {
-		respondWithJSON(w, 200, "")
-	} else {
-		respondWithJSON(w, 200, response)
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "")
+		return
 	}
+	user := User{
+		ID:        dbUser.ID,
+		CreatedAt: dbUser.CreatedAt,
+		UpdatedAt: dbUser.UpdatedAt,
+		Email:     dbUser.Email,
+		Username:  dbUser.Username.String,
+		Verified:  dbUser.EmailVerifiedAt.Valid,
+		ChirpyRed: dbUser.IsChirpyRed.Bool,
+	}
+	if !dbUser.EmailVerifiedAt.Valid && receivedData.Email != "" {
+		err = cfg.sendVerificationEmail(ctx, dbUser.ID, dbUser.Email)
+		if err != nil {
+			fmt.Printf("Error sending verification email: %v \n", err)
+		}
+	}
+	respondWithJSON(w, 200, user)
 }
 
 func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "")
-		return
-	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtKey)
+	userID, err := cfg.authorizedUser(r, scopeChirpsWrite)
 	if err != nil {
 		respondWithError(w, 403, "")
 		return
@@ -496,7 +843,7 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	chirp, err := cfg.database.GetChirp(ctx, id)
-	if err != nil {
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithError(w, 404, "")
 		return
 	}
@@ -504,14 +851,40 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 403, "")
 		return
 	}
-	err = cfg.database.DeleteChirp(ctx, chirp.ID)
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to delete Chirp")
+		return
+	}
+	defer tx.Rollback()
+	err = removeChirp(ctx, cfg.database.WithTx(tx), chirp.ID)
 	if err != nil {
 		respondWithError(w, 404, "")
 		return
 	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to delete Chirp")
+		return
+	}
 	w.WriteHeader(204)
 }
 
+// removeChirp deletes a chirp. Chirps that are replied to or quoted become
+// tombstones so the conversations around them survive; anything else is
+// removed outright, taking its plain rechirps with it. q should be bound to
+// the caller's transaction.
+func removeChirp(ctx context.Context, q *database.Queries, id uuid.UUID) error {
+	dependents, err := q.CountChirpDependents(ctx, id)
+	if err != nil {
+		return err
+	}
+	if dependents > 0 {
+		return tombstoneChirp(ctx, q, id)
+	}
+	return q.DeleteChirp(ctx, id)
+}
+
 func (cfg *apiConfig) polkaHook(w http.ResponseWriter, r *http.Request) {
 	type incomingPolkaEvent struct {
 		Event string `json:"event"`
@@ -561,6 +934,47 @@ func main() {
 	dbURL := os.Getenv("DB_URL")
 	jwtSecret := os.Getenv("SECRET_JWT_STRING")
 	polkaSecret := os.Getenv("POLKA_KEY")
+	trendingWindow, err := time.ParseDuration(os.Getenv("TRENDING_WINDOW"))
+	if err != nil {
+		trendingWindow = defaultTrendingWindow
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	requireVerifiedEmail := os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Error: invalid TRUSTED_PROXIES: %v", err)
+	}
+	argon2Params, err := auth.Argon2ParamsFromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	auth.SetArgon2Params(argon2Params)
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	var jwtKeys *auth.KeySet
+	if keysDir != "" {
+		jwtKeys, err = auth.LoadKeySet(keysDir, os.Getenv("JWT_SIGNING_KID"))
+		if err != nil {
+			log.Fatalf("Error: unable to load JWT keys: %v", err)
+		}
+	} else {
+		if len(jwtSecret) < auth.MinSecretLength {
+			log.Fatalf("Error: SECRET_JWT_STRING must be at least %d bytes", auth.MinSecretLength)
+		}
+		jwtKeys = auth.NewHMACKeySet(jwtSecret)
+	}
+	// Two-factor challenges and email verification links are HMAC-signed
+	// with their own secret, which has to outlive SECRET_JWT_STRING once
+	// access tokens are signed with the keys in JWT_KEYS_DIR.
+	purposeSecret := os.Getenv("PURPOSE_TOKEN_SECRET")
+	if purposeSecret == "" && keysDir == "" {
+		purposeSecret = jwtSecret
+	}
+	if len(purposeSecret) < auth.MinSecretLength {
+		log.Fatalf("Error: PURPOSE_TOKEN_SECRET must be at least %d bytes", auth.MinSecretLength)
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		//maybe handle it better later, ignore for now.
@@ -568,27 +982,104 @@ func main() {
 	}
 	defer db.Close()
 	dbQueries := database.New(db)
+	moderator, err := moderation.NewModerator(context.Background(), moderation.FromEnv(dbQueries))
+	if err != nil {
+		log.Fatalf("Error: unable to load moderation list: %v", err)
+	}
+	var lockoutStore lockout.Store = lockout.NewMemoryStore()
+	if os.Getenv("LOCKOUT_STORE") == "postgres" {
+		lockoutStore = lockout.PostgresStore{Queries: dbQueries}
+	}
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 	apiCfg := &apiConfig{
-		database: dbQueries,
-		jwtKey:   jwtSecret,
-		polkaKey: polkaSecret,
+		db:              db,
+		database:        dbQueries,
+		purposeSecret:   purposeSecret,
+		jwtKeys:         jwtKeys,
+		oidcProvider:    oidc.FromEnv(baseURL + "/api/oidc/callback"),
+		loginThrottle:   newLoginThrottle(lockoutStore),
+		polkaKey:        polkaSecret,
+		platform:        os.Getenv("PLATFORM"),
+		moderator:       moderator,
+		trendingWindow:  trendingWindow,
+		mailer:          mail,
+		baseURL:         baseURL,
+		requireVerified: requireVerifiedEmail,
+		trustedProxies:  trustedProxies,
 	}
 	SM := http.NewServeMux()
 	Server := &http.Server{Addr: ":8080", Handler: SM}
 	fileServer := http.StripPrefix("/app", http.FileServer(http.Dir(".")))
 	SM.Handle("/app/", apiCfg.middlewareMetricsInc(fileServer))
 	SM.HandleFunc("GET /api/healthz", healthzHandler)
-	SM.HandleFunc("GET /admin/metrics", apiCfg.metricsHandler)
-	SM.HandleFunc("POST /admin/reset", apiCfg.resetHandler)
+	SM.HandleFunc("GET /.well-known/jwks.json", apiCfg.jwksHandler)
+	SM.HandleFunc("GET /admin/metrics", apiCfg.requireRole(auth.RoleAdmin, apiCfg.metricsHandler))
+	SM.HandleFunc("POST /admin/reset", apiCfg.requireRole(auth.RoleAdmin, apiCfg.resetHandler))
+	SM.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.requireRole(auth.RoleAdmin, apiCfg.setUserRole))
+	SM.HandleFunc("PUT /admin/users/{userID}/suspension", apiCfg.requireRole(auth.RoleModerator, apiCfg.setUserSuspension))
+	SM.HandleFunc("DELETE /admin/users/{userID}/suspension", apiCfg.requireRole(auth.RoleModerator, apiCfg.liftUserSuspension))
+	SM.HandleFunc("PUT /admin/users/{userID}/shadow-ban", apiCfg.requireRole(auth.RoleModerator, apiCfg.shadowBanUser))
+	SM.HandleFunc("DELETE /admin/users/{userID}/shadow-ban", apiCfg.requireRole(auth.RoleModerator, apiCfg.liftShadowBan))
+	SM.HandleFunc("POST /admin/moderation/reload", apiCfg.requireRole(auth.RoleAdmin, apiCfg.reloadModeration))
+	SM.HandleFunc("GET /admin/reports", apiCfg.requireRole(auth.RoleModerator, apiCfg.getReports))
+	SM.HandleFunc("GET /admin/reports/{reportID}", apiCfg.requireRole(auth.RoleModerator, apiCfg.getReport))
+	SM.HandleFunc("POST /admin/reports/{reportID}/claim", apiCfg.requireRole(auth.RoleModerator, apiCfg.claimReport))
+	SM.HandleFunc("POST /admin/reports/{reportID}/resolve", apiCfg.requireRole(auth.RoleModerator, apiCfg.resolveReport))
+	SM.HandleFunc("POST /admin/reports/{reportID}/dismiss", apiCfg.requireRole(auth.RoleModerator, apiCfg.dismissReport))
 	SM.HandleFunc("POST /api/chirps", apiCfg.chirps)
 	SM.HandleFunc("GET /api/chirps", apiCfg.getChirps)
+	SM.HandleFunc("GET /api/chirps/search", apiCfg.searchChirps)
 	SM.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirp)
+	SM.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.updateChirp)
 	SM.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirp)
+	SM.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.reportChirp)
+	SM.HandleFunc("GET /api/chirps/{chirpID}/history", apiCfg.getChirpHistory)
+	SM.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.getChirpThread)
+	SM.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.likeChirp)
+	SM.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.unlikeChirp)
+	SM.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.rechirp)
+	SM.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.undoRechirp)
+	SM.HandleFunc("GET /api/hashtags/{tag}", apiCfg.getHashtagChirps)
+	SM.HandleFunc("GET /api/trending/hashtags", apiCfg.getTrendingHashtags)
 	SM.HandleFunc("POST /api/users", apiCfg.createUser)
 	SM.HandleFunc("PUT /api/users", apiCfg.updateUser)
+	SM.HandleFunc("GET /api/users/{username}", apiCfg.getUserProfile)
+	SM.HandleFunc("POST /api/users/verify", apiCfg.verifyEmail)
+	SM.HandleFunc("GET /api/users/verify", apiCfg.verifyEmailLink)
+	SM.HandleFunc("POST /api/users/verify/resend", apiCfg.resendVerification)
+	SM.HandleFunc("POST /api/users/{userID}/follow", apiCfg.followUser)
+	SM.HandleFunc("POST /api/users/{userID}/report", apiCfg.reportUser)
+	SM.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.unfollowUser)
+	SM.HandleFunc("POST /api/users/{userID}/block", apiCfg.blockUser)
+	SM.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.unblockUser)
+	SM.HandleFunc("POST /api/users/{userID}/mute", apiCfg.muteUser)
+	SM.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.unmuteUser)
+	SM.HandleFunc("GET /api/blocks", apiCfg.getBlockedUsers)
+	SM.HandleFunc("GET /api/mutes", apiCfg.getMutedUsers)
+	SM.HandleFunc("GET /api/users/{userID}/followers", apiCfg.getFollowers)
+	SM.HandleFunc("GET /api/users/{userID}/following", apiCfg.getFollowing)
+	SM.HandleFunc("GET /api/timeline", apiCfg.getTimeline)
+	SM.HandleFunc("GET /api/mentions", apiCfg.getMentions)
 	SM.HandleFunc("POST /api/login", apiCfg.login)
+	SM.HandleFunc("POST /api/login/2fa", apiCfg.loginTwoFactor)
+	SM.HandleFunc("GET /api/oidc/login", apiCfg.oidcLogin)
+	SM.HandleFunc("GET /api/oidc/callback", apiCfg.oidcCallback)
+	SM.HandleFunc("POST /api/2fa/setup", apiCfg.setupTwoFactor)
+	SM.HandleFunc("POST /api/2fa/enable", apiCfg.enableTwoFactor)
+	SM.HandleFunc("POST /api/2fa/disable", apiCfg.disableTwoFactor)
+	SM.HandleFunc("POST /api/password/forgot", apiCfg.forgotPassword)
+	SM.HandleFunc("POST /api/password/reset", apiCfg.resetPassword)
 	SM.HandleFunc("POST /api/refresh", apiCfg.refresh)
 	SM.HandleFunc("POST /api/revoke", apiCfg.revoke)
+	SM.HandleFunc("GET /api/sessions", apiCfg.getSessions)
+	SM.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.revokeSession)
+	SM.HandleFunc("POST /api/sessions/revoke-all", apiCfg.revokeAllSessions)
+	SM.HandleFunc("POST /api/tokens", apiCfg.createAPIToken)
+	SM.HandleFunc("GET /api/tokens", apiCfg.getAPITokens)
+	SM.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.revokeAPIToken)
 	SM.HandleFunc("POST /api/polka/webhooks", apiCfg.polkaHook)
 	err = Server.ListenAndServe()
 	if err != nil {
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/chirptext"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+// ChirpMention links an @username in a chirp body to the mentioned user.
+// Start and End are rune offsets into the body.
+type ChirpMention struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Start    int32     `json:"start"`
+	End      int32     `json:"end"`
+}
+
+// indexChirpText rebuilds the hashtag and mention indexes for a chirp body.
+// It runs on the transaction that writes the body so the indexes cannot
+// drift from it. Mentions of usernames nobody has claimed, or of users who
+// have blocked the author or been blocked by them, are dropped.
+func indexChirpText(ctx context.Context, qtx *database.Queries, chirpID, authorID uuid.UUID, body string) error {
+	err := qtx.DeleteChirpHashtags(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+	tags := chirptext.ExtractHashtags(body)
+	if len(tags) > 0 {
+		err = qtx.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+			ChirpID: chirpID,
+			Tags:    tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = qtx.DeleteChirpMentions(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+	mentions := chirptext.ExtractMentions(body)
+	if len(mentions) == 0 {
+		return nil
+	}
+	usernames := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		usernames = append(usernames, mention.Username)
+	}
+	resolved, err := qtx.ResolveUsernames(ctx, database.ResolveUsernamesParams{
+		Usernames: usernames,
+		AuthorID:  authorID,
+	})
+	if err != nil {
+		return err
+	}
+	userIDs := make(map[string]uuid.UUID, len(resolved))
+	for _, row := range resolved {
+		userIDs[row.Username] = row.ID
+	}
+	params := database.AddChirpMentionsParams{ChirpID: chirpID}
+	for _, mention := range mentions {
+		userID, ok := userIDs[mention.Username]
+		if !ok {
+			continue
+		}
+		params.UserIds = append(params.UserIds, userID)
+		params.StartOffsets = append(params.StartOffsets, int32(mention.Start))
+		params.EndOffsets = append(params.EndOffsets, int32(mention.End))
+	}
+	if len(params.UserIds) == 0 {
+		return nil
+	}
+	return qtx.AddChirpMentions(ctx, params)
+}
+
+// attachMentions loads the resolved mentions for a batch of chirps in one
+// query.
+func (cfg *apiConfig) attachMentions(ctx context.Context, chirps []*Chirp) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	rows, err := cfg.database.GetMentionsForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byChirp := make(map[uuid.UUID][]ChirpMention, len(chirps))
+	for _, row := range rows {
+		byChirp[row.ChirpID] = append(byChirp[row.ChirpID], ChirpMention{
+			UserID:   row.UserID,
+			Username: row.Username.String,
+			Start:    row.StartOffset,
+			End:      row.EndOffset,
+		})
+	}
+	for _, chirp := range chirps {
+		chirp.Mentions = byChirp[chirp.ID]
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getMentions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	page, err := parsePageParams(query)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	var dbChirps []database.Chirp
+	if query.Get("sort") == "desc" {
+		dbChirps, err = cfg.database.GetMentionChirpsDesc(ctx, database.GetMentionChirpsDescParams{
+			UserID:          userID,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	} else {
+		dbChirps, err = cfg.database.GetMentionChirps(ctx, database.GetMentionChirpsParams{
+			UserID:          userID,
+			CursorCreatedAt: page.cursorCreatedAt,
+			CursorID:        page.cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 400, "Unable to retrieve mentions")
+		return
+	}
+	chirps, err := cfg.newChirpPage(ctx, dbChirps, page.limit, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve mentions")
+		return
+	}
+	respondWithJSON(w, 200, chirps)
+}
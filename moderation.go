@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+)
+
+// flagChirp puts a chirp the content filter flagged into the report queue,
+// unless it is already waiting there.
+func flagChirp(ctx context.Context, q *database.Queries, chirp database.Chirp, words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+	return q.CreateFilterReport(ctx, database.CreateFilterReportParams{
+		ChirpID:        chirp.ID,
+		ChirpBody:      chirp.Body,
+		ReportedUserID: chirp.UserID,
+		Details:        strings.Join(words, ", "),
+	})
+}
+
+func (cfg *apiConfig) reloadModeration(w http.ResponseWriter, r *http.Request) {
+	type reloadResponse struct {
+		Words int `json:"words"`
+	}
+	err := cfg.moderator.Reload(r.Context())
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to reload moderation list")
+		return
+	}
+	respondWithJSON(w, 200, reloadResponse{Words: cfg.moderator.Filter().Len()})
+}
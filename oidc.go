@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/oidc"
+	"github.com/google/uuid"
+)
+
+const oidcStateCookie = "chirpy_oidc_state"
+
+var errIdentityEmailUnverified = errors.New("The identity provider did not supply a verified email address")
+
+// oidcLogin starts the authorization code flow. The state is kept both in
+// the database, next to the PKCE verifier and nonce, and in a cookie, so the
+// callback only completes in the browser that started it.
+func (cfg *apiConfig) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if cfg.oidcProvider == nil {
+		respondWithError(w, 404, "OIDC login is not configured")
+		return
+	}
+	ctx := r.Context()
+	state, err := oidc.RandomString()
+	if err != nil {
+		respondWithError(w, 500, "Unable to start sign-in")
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		respondWithError(w, 500, "Unable to start sign-in")
+		return
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		respondWithError(w, 500, "Unable to start sign-in")
+		return
+	}
+	err = cfg.database.DeleteExpiredOIDCStates(ctx)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+	}
+	err = cfg.database.CreateOIDCState(ctx, database.CreateOIDCStateParams{
+		StateHash:    auth.HashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Unable to start sign-in")
+		return
+	}
+	authURL, err := cfg.oidcProvider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 502, "Identity provider is unavailable")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (cfg *apiConfig) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if cfg.oidcProvider == nil {
+		respondWithError(w, 404, "OIDC login is not configured")
+		return
+	}
+	ctx := r.Context()
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		respondWithError(w, 401, "Sign-in was refused by the identity provider")
+		return
+	}
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		respondWithError(w, 401, "Sign-in state mismatch")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/oidc", MaxAge: -1})
+	stored, err := cfg.database.ConsumeOIDCState(ctx, auth.HashToken(state))
+	if err != nil {
+		respondWithError(w, 401, "Sign-in expired, please try again")
+		return
+	}
+	identity, err := cfg.oidcProvider.Exchange(ctx, query.Get("code"), stored.CodeVerifier, stored.Nonce)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 401, "Unable to verify sign-in")
+		return
+	}
+	userID, err := cfg.userForIdentity(ctx, identity)
+	if errors.Is(err, errIdentityEmailUnverified) {
+		respondWithError(w, 403, errIdentityEmailUnverified.Error())
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to sign in")
+		return
+	}
+	dbUser, err := cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve user details")
+		return
+	}
+	if isSuspended(dbUser.SuspendedUntil) {
+		respondWithSuspended(w, dbUser.SuspendedUntil)
+		return
+	}
+	totp, err := cfg.database.GetUserTOTP(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve user details")
+		return
+	}
+	if totp.TotpEnabledAt.Valid {
+		cfg.respondWithTwoFactorChallenge(w, userID)
+		return
+	}
+	user := User{
+		ID:        dbUser.ID,
+		CreatedAt: dbUser.CreatedAt,
+		UpdatedAt: dbUser.UpdatedAt,
+		Email:     dbUser.Email,
+		Username:  dbUser.Username.String,
+		Verified:  dbUser.EmailVerifiedAt.Valid,
+		ChirpyRed: dbUser.IsChirpyRed.Bool,
+	}
+	err = cfg.startSession(r, &user)
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
+	respondWithJSON(w, 200, user)
+}
+
+// userForIdentity finds the user linked to an external identity. The first
+// time an identity is seen it is linked to the account with the same email,
+// which is only trusted when the provider has verified it; if there is no
+// such account one is created with an unusable random password.
+//
+// A local account whose email was never verified may have been registered
+// by someone else in anticipation of the owner signing in, so linking to it
+// takes it over: its password is replaced and every credential issued for
+// it is revoked.
+func (cfg *apiConfig) userForIdentity(ctx context.Context, identity oidc.Identity) (uuid.UUID, error) {
+	userID, err := cfg.database.GetIdentityUser(ctx, database.GetIdentityUserParams{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+	})
+	if err == nil {
+		return userID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.UUID{}, err
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		return uuid.UUID{}, errIdentityEmailUnverified
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	existing, err := qtx.GetUserFromEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		userID = existing.ID
+		if !existing.EmailVerifiedAt.Valid {
+			err = resetUnverifiedAccount(ctx, qtx, userID)
+			if err != nil {
+				return uuid.UUID{}, err
+			}
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		hashed, err := unusablePasswordHash()
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+		created, err := qtx.CreateUser(ctx, database.CreateUserParams{
+			Email:          identity.Email,
+			HashedPassword: hashed,
+		})
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+		userID = created.ID
+	default:
+		return uuid.UUID{}, err
+	}
+	_, err = qtx.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{ID: userID, Email: identity.Email})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	err = qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		UserID:  userID,
+		Email:   identity.Email,
+	})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return userID, tx.Commit()
+}
+
+// unusablePasswordHash hashes a random password nobody knows, for accounts
+// that sign in through the identity provider.
+func unusablePasswordHash() (string, error) {
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return auth.HashPassword(password)
+}
+
+// resetUnverifiedAccount strips every way into an account other than the
+// identity being linked: the password, refresh tokens, API tokens and any
+// second factor set up before the email was proven.
+func resetUnverifiedAccount(ctx context.Context, qtx *database.Queries, userID uuid.UUID) error {
+	hashed, err := unusablePasswordHash()
+	if err != nil {
+		return err
+	}
+	err = qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		HashedPassword: hashed,
+		ID:             userID,
+	})
+	if err != nil {
+		return err
+	}
+	err = qtx.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = qtx.RevokeUserAPITokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = qtx.DisableTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return qtx.DeleteRecoveryCodes(ctx, userID)
+}
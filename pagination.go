@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type pageParams struct {
+	cursorCreatedAt sql.NullTime
+	cursorID        uuid.NullUUID
+	limit           int32
+}
+
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor"`
+}
+
+// userEntry is a user listed by when their relation to someone began, such
+// as a follower or a blocked user.
+type userEntry interface {
+	position() (time.Time, uuid.UUID)
+}
+
+type userPage[E userEntry] struct {
+	Users      []E    `json:"users"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// encodeCursor packs the keyset position (created_at, id) of the last item
+// on a page into an opaque string for the next request.
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%d:%s", createdAt.UnixMicro(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+	micros, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return time.Time{}, uuid.UUID{}, fmt.Errorf("malformed cursor")
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+	return time.UnixMicro(usec).UTC(), parsedID, nil
+}
+
+func parseLimit(query url.Values) (int32, error) {
+	limit := query.Get("limit")
+	if limit == "" {
+		return defaultPageSize, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("Invalid limit")
+	}
+	return int32(min(n, maxPageSize)), nil
+}
+
+func parsePageParams(query url.Values) (pageParams, error) {
+	limit, err := parseLimit(query)
+	if err != nil {
+		return pageParams{}, err
+	}
+	params := pageParams{limit: limit}
+	if cursor := query.Get("cursor"); cursor != "" {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return pageParams{}, fmt.Errorf("Invalid cursor")
+		}
+		params.cursorCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+		params.cursorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	return params, nil
+}
+
+// fetchSize asks the database for one row more than the page holds, so the
+// handler can tell whether a next page exists without a second query.
+func (p pageParams) fetchSize() int32 {
+	return p.limit + 1
+}
+
+func (cfg *apiConfig) newChirpPage(ctx context.Context, dbChirps []database.Chirp, limit int32, viewer uuid.NullUUID) (chirpPage, error) {
+	page := chirpPage{Chirps: []Chirp{}}
+	if len(dbChirps) > int(limit) {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromDB(dbChirp))
+	}
+	chirps := make([]*Chirp, 0, len(page.Chirps))
+	for i := range page.Chirps {
+		chirps = append(chirps, &page.Chirps[i])
+	}
+	err := cfg.decorateChirps(ctx, chirps, viewer)
+	if err != nil {
+		return chirpPage{}, err
+	}
+	return page, nil
+}
+
+func newUserPage[E userEntry](entries []E, limit int32) userPage[E] {
+	page := userPage[E]{Users: []E{}}
+	if len(entries) > int(limit) {
+		entries = entries[:limit]
+		page.NextCursor = encodeCursor(entries[len(entries)-1].position())
+	}
+	page.Users = append(page.Users, entries...)
+	return page
+}
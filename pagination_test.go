@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("5f8a1c2e-0b4d-4e6f-9a1b-2c3d4e5f6a7b")
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{name: "utc", createdAt: time.Date(2026, 3, 1, 12, 30, 45, 123456000, time.UTC)},
+		{name: "other zone", createdAt: time.Date(2026, 3, 1, 12, 30, 45, 0, time.FixedZone("EST", -5*3600))},
+		{name: "before 1970", createdAt: time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt, gotID, err := decodeCursor(encodeCursor(tt.createdAt, id))
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if !createdAt.Equal(tt.createdAt) || gotID != id {
+				t.Errorf("decodeCursor = (%v, %v), want (%v, %v)", createdAt, gotID, tt.createdAt, id)
+			}
+		})
+	}
+}
+
+func TestCursorTruncatesToMicroseconds(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	got, _, err := decodeCursor(encodeCursor(createdAt, uuid.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Postgres timestamps hold microseconds, so that is all a cursor keeps.
+	if want := createdAt.Truncate(time.Microsecond); !got.Equal(want) {
+		t.Errorf("decodeCursor time = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeCursorRejectsMalformed(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "no separator", cursor: encode("1700000000000000")},
+		{name: "bad timestamp", cursor: encode("yesterday:5f8a1c2e-0b4d-4e6f-9a1b-2c3d4e5f6a7b")},
+		{name: "bad id", cursor: encode("1700000000000000:not-a-uuid")},
+		{name: "empty", cursor: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCursor(tt.cursor)
+			if err == nil {
+				t.Errorf("decodeCursor(%q) succeeded, want error", tt.cursor)
+			}
+		})
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	cursor := encodeCursor(time.Now(), uuid.New())
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int32
+		wantCursor bool
+		wantErr    bool
+	}{
+		{name: "defaults", query: "", wantLimit: defaultPageSize},
+		{name: "explicit limit", query: "limit=5", wantLimit: 5},
+		{name: "limit capped", query: "limit=100000", wantLimit: maxPageSize},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "non-numeric limit", query: "limit=ten", wantErr: true},
+		{name: "cursor", query: "cursor=" + cursor, wantLimit: defaultPageSize, wantCursor: true},
+		{name: "bad cursor", query: "cursor=garbage", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := parsePageParams(query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePageParams succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePageParams: %v", err)
+			}
+			if params.limit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", params.limit, tt.wantLimit)
+			}
+			if params.cursorCreatedAt.Valid != tt.wantCursor || params.cursorID.Valid != tt.wantCursor {
+				t.Errorf("cursor set = %v/%v, want %v", params.cursorCreatedAt.Valid, params.cursorID.Valid, tt.wantCursor)
+			}
+		})
+	}
+}
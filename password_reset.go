@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/mailer"
+)
+
+func (cfg *apiConfig) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	type forgotRequest struct {
+		Email string `json:"email"`
+	}
+	ctx := r.Context()
+	request := forgotRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	// Answer the same way whether or not the account exists, so the endpoint
+	// cannot be used to discover registered emails.
+	dbUser, err := cfg.database.GetUserFromEmail(ctx, request.Email)
+	if err != nil {
+		w.WriteHeader(202)
+		return
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, 500, "Unable to create reset token")
+		return
+	}
+	err = cfg.database.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    dbUser.ID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to create reset token")
+		return
+	}
+	link := fmt.Sprintf("%s/app/reset-password.html?token=%s", cfg.baseURL, url.QueryEscape(token))
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      dbUser.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Choose a new password at %s\nor send this token to POST /api/password/reset:\n\n%s\n\n"+
+			"The token works once and expires in 30 minutes. If this wasn't you, ignore this email.\n",
+			link, token),
+	})
+	if err != nil {
+		fmt.Printf("Error sending reset email: %v \n", err)
+	}
+	w.WriteHeader(202)
+}
+
+func (cfg *apiConfig) resetPassword(w http.ResponseWriter, r *http.Request) {
+	type resetRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	ctx := r.Context()
+	request := resetRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	userID, err := qtx.ConsumePasswordResetToken(ctx, auth.HashToken(request.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 401, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	// A rejected password rolls the transaction back, so the token can be
+	// used again with a stronger one.
+	dbUser, err := qtx.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	err = auth.CheckPasswordStrength(request.Password, dbUser.Email, dbUser.Username.String)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	hashedPass, err := auth.HashPassword(request.Password)
+	if err != nil {
+		respondWithError(w, 400, "Unable to change password, faulty password")
+		return
+	}
+	err = qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		HashedPassword: hashedPass,
+		ID:             userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	err = qtx.InvalidatePasswordResetTokens(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	err = qtx.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to reset password")
+		return
+	}
+	w.WriteHeader(204)
+}
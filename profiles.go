@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/chirptext"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	CreatedAt   time.Time `json:"created_at"`
+	ChirpCount  int64     `json:"chirp_count"`
+	ChirpyRed   bool      `json:"is_chirpy_red"`
+}
+
+// isUniqueViolation reports whether err is Postgres refusing a duplicate
+// value for a unique column or index.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// validateProfile checks the optional profile fields sent to createUser and
+// updateUser. Nil fields are left unchanged.
+func validateProfile(username, displayName, bio *string) error {
+	if username != nil {
+		err := chirptext.ValidateUsername(*username)
+		if err != nil {
+			return err
+		}
+	}
+	if displayName != nil && utf8.RuneCountInString(*displayName) > maxDisplayNameLength {
+		return fmt.Errorf("Display name must be at most %d characters long", maxDisplayNameLength)
+	}
+	if bio != nil && utf8.RuneCountInString(*bio) > maxBioLength {
+		return fmt.Errorf("Bio must be at most %d characters long", maxBioLength)
+	}
+	return nil
+}
+
+func nullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
+
+func (cfg *apiConfig) getUserProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	row, err := cfg.database.GetUserProfile(ctx, r.PathValue("username"))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve profile")
+		return
+	}
+	profile := Profile{
+		ID:          row.ID,
+		Username:    row.Username.String,
+		DisplayName: row.DisplayName,
+		Bio:         row.Bio,
+		CreatedAt:   row.CreatedAt,
+		ChirpCount:  row.ChirpCount,
+		ChirpyRed:   row.IsChirpyRed.Bool,
+	}
+	respondWithJSON(w, 200, profile)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+// resolveOriginal looks up the chirp a rechirp, quote or reply should point
+// at. Rechirps are followed through to the chirp they amplify, and deleted
+// chirps cannot be referenced.
+func (cfg *apiConfig) resolveOriginal(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.database.GetChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chirp.RechirpOf.Valid {
+		chirp, err = cfg.database.GetChirp(ctx, chirp.RechirpOf.UUID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if chirp.DeletedAt.Valid {
+		return database.Chirp{}, fmt.Errorf("chirp %v is deleted", chirp.ID)
+	}
+	return chirp, nil
+}
+
+// embedOriginals attaches the chirp each rechirp or quote points at, loading
+// all of them in one query. Originals the viewer may not see are embedded as
+// unavailable placeholders.
+func (cfg *apiConfig) embedOriginals(ctx context.Context, chirps []*Chirp, viewer uuid.NullUUID) ([]*Chirp, error) {
+	var ids []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			ids = append(ids, *chirp.RechirpOf)
+		} else if chirp.QuoteOf != nil {
+			ids = append(ids, *chirp.QuoteOf)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	dbOriginals, err := cfg.database.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{
+		Ids:      ids,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	originals := make(map[uuid.UUID]*Chirp, len(dbOriginals))
+	embedded := make([]*Chirp, 0, len(dbOriginals))
+	for _, dbOriginal := range dbOriginals {
+		original := chirpFromDB(dbOriginal)
+		originals[original.ID] = &original
+		embedded = append(embedded, &original)
+	}
+	for _, chirp := range chirps {
+		var originalID uuid.UUID
+		if chirp.RechirpOf != nil {
+			originalID = *chirp.RechirpOf
+		} else if chirp.QuoteOf != nil {
+			originalID = *chirp.QuoteOf
+		} else {
+			continue
+		}
+		chirp.Original = originals[originalID]
+		if chirp.Original == nil {
+			chirp.Original = &Chirp{ID: originalID, Unavailable: true}
+		}
+	}
+	return embedded, nil
+}
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	err = cfg.ensureEmailVerified(ctx, userID)
+	if err != nil {
+		respondWithError(w, 403, errEmailNotVerified.Error())
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	original, err := cfg.resolveOriginal(ctx, id)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	blocked, err := cfg.blockedBetween(ctx, userID, original.UserID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to rechirp")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, errBlocked.Error())
+		return
+	}
+	rechirpOf := uuid.NullUUID{UUID: original.ID, Valid: true}
+	dbChirp, err := cfg.database.GetRechirp(ctx, database.GetRechirpParams{
+		UserID:    userID,
+		RechirpOf: rechirpOf,
+	})
+	code := 200
+	if err != nil {
+		dbChirp, err = cfg.database.CreateRechirp(ctx, database.CreateRechirpParams{
+			UserID:    userID,
+			RechirpOf: rechirpOf,
+		})
+		if err != nil {
+			fmt.Printf("Error %v", err)
+			respondWithError(w, 409, "Unable to rechirp")
+			return
+		}
+		code = 201
+	}
+	chirp := chirpFromDB(dbChirp)
+	err = cfg.decorateChirps(ctx, []*Chirp{&chirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Unable to rechirp")
+		return
+	}
+	respondWithJSON(w, code, chirp)
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	original, err := cfg.resolveOriginal(ctx, id)
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	removed, err := cfg.database.DeleteRechirp(ctx, database.DeleteRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to undo rechirp")
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, 404, "")
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxReportDetailsLength = 500
+	maxReportNoteLength    = 1000
+	defaultSuspension      = 7 * 24 * time.Hour
+	maxSuspension          = 10 * 365 * 24 * time.Hour
+)
+
+// reportReasons are the reason codes users may choose. The content filter
+// files its own reports with the reason "content_filter".
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual_content", "self_harm", "impersonation", "other"}
+
+const (
+	decisionDeleteChirp = "delete_chirp"
+	decisionSuspendUser = "suspend_user"
+	decisionShadowBan   = "shadow_ban"
+	decisionDismiss     = "dismiss"
+)
+
+var errReportNotOpen = errors.New("Report is closed or claimed by another moderator")
+
+type Report struct {
+	ID             uuid.UUID            `json:"id"`
+	ReporterID     *uuid.UUID           `json:"reporter_id"`
+	ChirpID        *uuid.UUID           `json:"chirp_id,omitempty"`
+	ChirpBody      string               `json:"chirp_body,omitempty"`
+	ReportedUserID uuid.UUID            `json:"reported_user_id"`
+	Reason         string               `json:"reason"`
+	Details        string               `json:"details"`
+	Status         string               `json:"status"`
+	ClaimedBy      *uuid.UUID           `json:"claimed_by,omitempty"`
+	ClaimedAt      *time.Time           `json:"claimed_at,omitempty"`
+	ResolvedAt     *time.Time           `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	Decisions      []ModerationDecision `json:"decisions,omitempty"`
+}
+
+type ModerationDecision struct {
+	ID             uuid.UUID  `json:"id"`
+	ModeratorID    *uuid.UUID `json:"moderator_id"`
+	Action         string     `json:"action"`
+	Note           string     `json:"note"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type reportPage struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor"`
+}
+
+func nullableUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func reportFromDB(dbReport database.Report) Report {
+	return Report{
+		ID:             dbReport.ID,
+		ReporterID:     nullableUUID(dbReport.ReporterID),
+		ChirpID:        nullableUUID(dbReport.ChirpID),
+		ChirpBody:      dbReport.ChirpBody.String,
+		ReportedUserID: dbReport.ReportedUserID,
+		Reason:         dbReport.Reason,
+		Details:        dbReport.Details,
+		Status:         dbReport.Status,
+		ClaimedBy:      nullableUUID(dbReport.ClaimedBy),
+		ClaimedAt:      nullableTime(dbReport.ClaimedAt),
+		ResolvedAt:     nullableTime(dbReport.ResolvedAt),
+		CreatedAt:      dbReport.CreatedAt,
+	}
+}
+
+func decisionFromDB(dbDecision database.ModerationDecision) ModerationDecision {
+	return ModerationDecision{
+		ID:             dbDecision.ID,
+		ModeratorID:    nullableUUID(dbDecision.ModeratorID),
+		Action:         dbDecision.Action,
+		Note:           dbDecision.Note,
+		SuspendedUntil: nullableTime(dbDecision.SuspendedUntil),
+		CreatedAt:      dbDecision.CreatedAt,
+	}
+}
+
+type reportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+func decodeReportRequest(r *http.Request) (reportRequest, error) {
+	request := reportRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil {
+		return reportRequest{}, errors.New("Unable to process request")
+	}
+	if !slices.Contains(reportReasons, request.Reason) {
+		return reportRequest{}, fmt.Errorf("Reason must be one of: %s", strings.Join(reportReasons, ", "))
+	}
+	request.Details = strings.TrimSpace(request.Details)
+	if utf8.RuneCountInString(request.Details) > maxReportDetailsLength {
+		return reportRequest{}, fmt.Errorf("Details must be at most %d characters", maxReportDetailsLength)
+	}
+	return request, nil
+}
+
+func (cfg *apiConfig) fileReport(w http.ResponseWriter, r *http.Request, params database.CreateReportParams) {
+	dbReport, err := cfg.database.CreateReport(r.Context(), params)
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "You have already reported this")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to file report")
+		return
+	}
+	report := reportFromDB(dbReport)
+	// Reporters see what they filed, not the snapshot kept for moderators.
+	report.ChirpBody = ""
+	respondWithJSON(w, 201, report)
+}
+
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reporterID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	request, err := decodeReportRequest(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	chirp, err := cfg.database.GetChirp(ctx, chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	if chirp.UserID == reporterID {
+		respondWithError(w, 400, "You cannot report your own Chirp")
+		return
+	}
+	cfg.fileReport(w, r, database.CreateReportParams{
+		ReporterID:     uuid.NullUUID{UUID: reporterID, Valid: true},
+		ChirpID:        uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		ChirpBody:      sql.NullString{String: chirp.Body, Valid: true},
+		ReportedUserID: chirp.UserID,
+		Reason:         request.Reason,
+		Details:        request.Details,
+	})
+}
+
+func (cfg *apiConfig) reportUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reporterID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	request, err := decodeReportRequest(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if userID == reporterID {
+		respondWithError(w, 400, "You cannot report yourself")
+		return
+	}
+	_, err = cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	cfg.fileReport(w, r, database.CreateReportParams{
+		ReporterID:     uuid.NullUUID{UUID: reporterID, Valid: true},
+		ReportedUserID: userID,
+		Reason:         request.Reason,
+		Details:        request.Details,
+	})
+}
+
+// getReports lists the queue oldest first. Without ?status= it shows the
+// reports still waiting for a decision, claimed or not.
+func (cfg *apiConfig) getReports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	page, err := parsePageParams(query)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	status := sql.NullString{}
+	if s := query.Get("status"); s != "" {
+		if !slices.Contains([]string{"open", "claimed", "resolved", "dismissed"}, s) {
+			respondWithError(w, 400, "Invalid status")
+			return
+		}
+		status = sql.NullString{String: s, Valid: true}
+	}
+	dbReports, err := cfg.database.GetReports(ctx, database.GetReportsParams{
+		Status:          status,
+		CursorCreatedAt: page.cursorCreatedAt,
+		CursorID:        page.cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve reports")
+		return
+	}
+	result := reportPage{Reports: []Report{}}
+	if len(dbReports) > int(page.limit) {
+		dbReports = dbReports[:page.limit]
+		last := dbReports[len(dbReports)-1]
+		result.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	for _, dbReport := range dbReports {
+		result.Reports = append(result.Reports, reportFromDB(dbReport))
+	}
+	respondWithJSON(w, 200, result)
+}
+
+func (cfg *apiConfig) reportWithDecisions(ctx context.Context, dbReport database.Report) (Report, error) {
+	report := reportFromDB(dbReport)
+	dbDecisions, err := cfg.database.GetReportDecisions(ctx, dbReport.ID)
+	if err != nil {
+		return Report{}, err
+	}
+	for _, dbDecision := range dbDecisions {
+		report.Decisions = append(report.Decisions, decisionFromDB(dbDecision))
+	}
+	return report, nil
+}
+
+func (cfg *apiConfig) getReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 404, "Report not found")
+		return
+	}
+	dbReport, err := cfg.database.GetReport(ctx, reportID)
+	if err != nil {
+		respondWithError(w, 404, "Report not found")
+		return
+	}
+	report, err := cfg.reportWithDecisions(ctx, dbReport)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve report")
+		return
+	}
+	respondWithJSON(w, 200, report)
+}
+
+func (cfg *apiConfig) claimReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	moderatorID := requestUser(r)
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 404, "Report not found")
+		return
+	}
+	dbReport, err := cfg.database.ClaimReport(ctx, database.ClaimReportParams{
+		ModeratorID: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		ID:          reportID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, errReportNotOpen.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Unable to claim report")
+		return
+	}
+	respondWithJSON(w, 200, reportFromDB(dbReport))
+}
+
+type decisionRequest struct {
+	Action     string `json:"action"`
+	Note       string `json:"note"`
+	SuspendFor string `json:"suspend_for"`
+}
+
+func (cfg *apiConfig) resolveReport(w http.ResponseWriter, r *http.Request) {
+	request := decisionRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	cfg.decideReport(w, r, request)
+}
+
+func (cfg *apiConfig) dismissReport(w http.ResponseWriter, r *http.Request) {
+	request := decisionRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	request.Action = decisionDismiss
+	cfg.decideReport(w, r, request)
+}
+
+// decideReport closes the report, carries out the moderator's decision and
+// records it in one transaction, so a failed action leaves the report open.
+func (cfg *apiConfig) decideReport(w http.ResponseWriter, r *http.Request, request decisionRequest) {
+	ctx := r.Context()
+	moderatorID := requestUser(r)
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 404, "Report not found")
+		return
+	}
+	if utf8.RuneCountInString(request.Note) > maxReportNoteLength {
+		respondWithError(w, 400, fmt.Sprintf("Note must be at most %d characters", maxReportNoteLength))
+		return
+	}
+	suspendFor := defaultSuspension
+	if request.SuspendFor != "" {
+		suspendFor, err = time.ParseDuration(request.SuspendFor)
+		if err != nil || suspendFor <= 0 || suspendFor > maxSuspension {
+			respondWithError(w, 400, "Invalid suspend_for")
+			return
+		}
+	}
+	status := "resolved"
+	switch request.Action {
+	case decisionDeleteChirp, decisionSuspendUser, decisionShadowBan:
+	case decisionDismiss:
+		status = "dismissed"
+	default:
+		respondWithError(w, 400, "Action must be one of: delete_chirp, suspend_user, shadow_ban, dismiss")
+		return
+	}
+	dbReport, err := cfg.database.GetReport(ctx, reportID)
+	if err != nil {
+		respondWithError(w, 404, "Report not found")
+		return
+	}
+	if request.Action == decisionDeleteChirp && !dbReport.ChirpID.Valid {
+		respondWithError(w, 400, "Report is not about a Chirp")
+		return
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to record decision")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	// Closing the report first locks its row, so a second moderator deciding
+	// the same report waits here and then gets a 409 instead of acting too.
+	moderator := uuid.NullUUID{UUID: moderatorID, Valid: true}
+	dbReport, err = qtx.CloseReport(ctx, database.CloseReportParams{
+		Status:      status,
+		ModeratorID: moderator,
+		ID:          reportID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, errReportNotOpen.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Unable to record decision")
+		return
+	}
+	suspendedUntil := sql.NullTime{}
+	switch request.Action {
+	case decisionDeleteChirp:
+		chirp, err := qtx.GetChirp(ctx, dbReport.ChirpID.UUID)
+		if err == nil && !chirp.DeletedAt.Valid {
+			err = removeChirp(ctx, qtx, chirp.ID)
+		} else if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+		if err != nil {
+			fmt.Printf("Error %v", err)
+			respondWithError(w, 500, "Unable to delete Chirp")
+			return
+		}
+	case decisionSuspendUser:
+		suspendedUntil = sql.NullTime{Time: time.Now().UTC().Add(suspendFor), Valid: true}
+		_, err = suspendUser(ctx, qtx, dbReport.ReportedUserID, suspendedUntil)
+		if err != nil {
+			fmt.Printf("Error %v", err)
+			respondWithError(w, 500, "Unable to suspend user")
+			return
+		}
+	case decisionShadowBan:
+		_, err = qtx.SetUserShadowBan(ctx, database.SetUserShadowBanParams{
+			ShadowBanned: true,
+			ID:           dbReport.ReportedUserID,
+		})
+		if err != nil {
+			fmt.Printf("Error %v", err)
+			respondWithError(w, 500, "Unable to shadow-ban user")
+			return
+		}
+	}
+	_, err = qtx.CreateModerationDecision(ctx, database.CreateModerationDecisionParams{
+		ReportID:       reportID,
+		ModeratorID:    moderator,
+		Action:         request.Action,
+		Note:           strings.TrimSpace(request.Note),
+		SuspendedUntil: suspendedUntil,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to record decision")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to record decision")
+		return
+	}
+	report, err := cfg.reportWithDecisions(ctx, dbReport)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve report")
+		return
+	}
+	respondWithJSON(w, 200, report)
+}
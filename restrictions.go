@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+// isSuspended reports whether a suspended_until value is still in force.
+func isSuspended(suspendedUntil sql.NullTime) bool {
+	return suspendedUntil.Valid && time.Now().UTC().Before(suspendedUntil.Time)
+}
+
+func respondWithSuspended(w http.ResponseWriter, suspendedUntil sql.NullTime) {
+	respondWithError(w, 403, fmt.Sprintf("Account suspended until %s", suspendedUntil.Time.UTC().Format(time.RFC3339)))
+}
+
+// suspendUser sets or clears a user's suspension. Setting one also revokes
+// every refresh token, so the user is signed out everywhere once their
+// current access token expires. It reports false if there is no such user.
+// q should be bound to the caller's transaction.
+func suspendUser(ctx context.Context, q *database.Queries, userID uuid.UUID, suspendedUntil sql.NullTime) (bool, error) {
+	rows, err := q.SuspendUser(ctx, database.SuspendUserParams{
+		SuspendedUntil: suspendedUntil,
+		ID:             userID,
+	})
+	if err != nil || rows == 0 {
+		return false, err
+	}
+	if suspendedUntil.Valid {
+		err = q.RevokeUserRefreshTokens(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// changeSuspension runs suspendUser in its own transaction.
+func (cfg *apiConfig) changeSuspension(ctx context.Context, userID uuid.UUID, suspendedUntil sql.NullTime) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	found, err := suspendUser(ctx, cfg.database.WithTx(tx), userID, suspendedUntil)
+	if err != nil || !found {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+type userRestrictions struct {
+	ID             uuid.UUID  `json:"id"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	ShadowBanned   bool       `json:"shadow_banned"`
+}
+
+func (cfg *apiConfig) respondWithRestrictions(w http.ResponseWriter, ctx context.Context, userID uuid.UUID) {
+	dbUser, err := cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve user details")
+		return
+	}
+	restrictions := userRestrictions{
+		ID:           dbUser.ID,
+		ShadowBanned: dbUser.ShadowBanned,
+	}
+	if isSuspended(dbUser.SuspendedUntil) {
+		restrictions.SuspendedUntil = &dbUser.SuspendedUntil.Time
+	}
+	respondWithJSON(w, 200, restrictions)
+}
+
+func (cfg *apiConfig) setUserSuspension(w http.ResponseWriter, r *http.Request) {
+	type suspensionRequest struct {
+		SuspendFor string `json:"suspend_for"`
+	}
+	ctx := r.Context()
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	request := suspensionRequest{}
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	suspendFor := defaultSuspension
+	if request.SuspendFor != "" {
+		suspendFor, err = time.ParseDuration(request.SuspendFor)
+		if err != nil || suspendFor <= 0 || suspendFor > maxSuspension {
+			respondWithError(w, 400, "Invalid suspend_for")
+			return
+		}
+	}
+	found, err := cfg.changeSuspension(ctx, userID, sql.NullTime{Time: time.Now().UTC().Add(suspendFor), Valid: true})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to suspend user")
+		return
+	}
+	if !found {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	cfg.respondWithRestrictions(w, ctx, userID)
+}
+
+func (cfg *apiConfig) liftUserSuspension(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	found, err := cfg.changeSuspension(ctx, userID, sql.NullTime{})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to lift suspension")
+		return
+	}
+	if !found {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	cfg.respondWithRestrictions(w, ctx, userID)
+}
+
+func (cfg *apiConfig) shadowBanUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setShadowBan(w, r, true)
+}
+
+func (cfg *apiConfig) liftShadowBan(w http.ResponseWriter, r *http.Request) {
+	cfg.setShadowBan(w, r, false)
+}
+
+// setShadowBan hides or restores a user's chirps in everyone else's
+// listings. The user is not told and still sees their own chirps.
+func (cfg *apiConfig) setShadowBan(w http.ResponseWriter, r *http.Request, banned bool) {
+	ctx := r.Context()
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	rows, err := cfg.database.SetUserShadowBan(ctx, database.SetUserShadowBanParams{
+		ShadowBanned: banned,
+		ID:           userID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update user")
+		return
+	}
+	if rows == 0 {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	cfg.respondWithRestrictions(w, ctx, userID)
+}
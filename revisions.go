@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *apiConfig) updateChirp(w http.ResponseWriter, r *http.Request) {
+	type chirpEdit struct {
+		Body string `json:"body"`
+	}
+	ctx := r.Context()
+	userID, err := cfg.authorizedUser(r, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+	err = cfg.ensureEmailVerified(ctx, userID)
+	if err != nil {
+		respondWithError(w, 403, errEmailNotVerified.Error())
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	edit := chirpEdit{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&edit)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process Chirp")
+		return
+	}
+	var flags []string
+	edit.Body, flags, err = cfg.prepareChirpBody(edit.Body)
+	if err != nil {
+		respondWithChirpBodyError(w, err)
+		return
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	current, err := qtx.GetChirpForUpdate(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	if current.DeletedAt.Valid {
+		respondWithError(w, 404, "")
+		return
+	}
+	if current.UserID != userID {
+		respondWithError(w, 403, "")
+		return
+	}
+	if current.RechirpOf.Valid {
+		respondWithError(w, 400, "Rechirps cannot be edited")
+		return
+	}
+	_, err = qtx.CreateChirpRevision(ctx, database.CreateChirpRevisionParams{
+		ChirpID:   current.ID,
+		Body:      current.Body,
+		CreatedAt: current.UpdatedAt,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	dbChirp, err := qtx.UpdateChirpBody(ctx, database.UpdateChirpBodyParams{
+		Body: edit.Body,
+		ID:   current.ID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	err = indexChirpText(ctx, qtx, dbChirp.ID, dbChirp.UserID, dbChirp.Body)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	err = flagChirp(ctx, qtx, dbChirp, flags)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	chirp := chirpFromDB(dbChirp)
+	err = cfg.decorateChirps(ctx, []*Chirp{&chirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Unable to update Chirp")
+		return
+	}
+	respondWithJSON(w, 200, chirp)
+}
+
+func (cfg *apiConfig) getChirpHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Unable to parse request")
+		return
+	}
+	row, err := cfg.database.GetChirpForViewer(ctx, database.GetChirpForViewerParams{
+		ViewerID: cfg.optionalViewer(r),
+		ID:       id,
+	})
+	if err != nil || row.Chirp.DeletedAt.Valid || !row.Visible {
+		respondWithError(w, 404, "")
+		return
+	}
+	dbRevisions, err := cfg.database.GetChirpRevisions(ctx, id)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve Chirp history")
+		return
+	}
+	revisions := []ChirpRevision{}
+	for _, dbRevision := range dbRevisions {
+		revisions = append(revisions, ChirpRevision{
+			ID:         dbRevision.ID,
+			ChirpID:    dbRevision.ChirpID,
+			Body:       dbRevision.Body,
+			CreatedAt:  dbRevision.CreatedAt,
+			ReplacedAt: dbRevision.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, 200, revisions)
+}
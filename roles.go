@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+type requestUserKey struct{}
+
+// requireRole wraps an admin handler so it only runs for a signed-in user
+// whose access token carries at least the required role. The role is checked
+// again against the database, so a demotion or suspension takes effect at
+// once rather than when the token expires. The caller's id is available to
+// the handler through requestUser.
+func (cfg *apiConfig) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, 401, "Unauthorized")
+			return
+		}
+		userID, userRole, err := auth.ValidateJWTWithRole(token, cfg.jwtKeys)
+		if err != nil {
+			respondWithError(w, 401, "Unauthorized")
+			return
+		}
+		if !auth.HasRole(userRole, role) {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+		dbUser, err := cfg.database.GetUser(r.Context(), userID)
+		if err != nil || !auth.HasRole(dbUser.Role, role) {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+		if isSuspended(dbUser.SuspendedUntil) {
+			respondWithSuspended(w, dbUser.SuspendedUntil)
+			return
+		}
+		ctx := context.WithValue(r.Context(), requestUserKey{}, userID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requestUser returns the user requireRole authenticated.
+func requestUser(r *http.Request) uuid.UUID {
+	userID, _ := r.Context().Value(requestUserKey{}).(uuid.UUID)
+	return userID
+}
+
+func (cfg *apiConfig) setUserRole(w http.ResponseWriter, r *http.Request) {
+	type roleRequest struct {
+		Role string `json:"role"`
+	}
+	type roleResponse struct {
+		ID   uuid.UUID `json:"id"`
+		Role string    `json:"role"`
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+	var params roleRequest
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request")
+		return
+	}
+	if !auth.ValidRole(params.Role) {
+		respondWithError(w, 400, "Unknown role")
+		return
+	}
+	// Demoting yourself could leave the server without an admin.
+	if userID == requestUser(r) && params.Role != auth.RoleAdmin {
+		respondWithError(w, 400, "Admins cannot change their own role")
+		return
+	}
+	ctx := r.Context()
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to update role")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	rows, err := qtx.SetUserRole(ctx, database.SetUserRoleParams{
+		Role: params.Role,
+		ID:   userID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update role")
+		return
+	}
+	if rows == 0 {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	// Sign the user out everywhere so their sessions start over under the
+	// new role.
+	err = qtx.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to update role")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to update role")
+		return
+	}
+	respondWithJSON(w, 200, roleResponse{ID: userID, Role: params.Role})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxSearchOffset = 10000
+
+// buildTSQuery turns a user's search string into to_tsquery syntax. Quoted
+// text becomes a phrase, a trailing '*' makes a word match as a prefix, and
+// everything else must all match. Only letters and digits reach Postgres, so
+// the operators in the result are always ours.
+func buildTSQuery(q string) (string, error) {
+	var terms []string
+	segments := strings.Split(q, `"`)
+	for i, segment := range segments {
+		var lexemes []string
+		for _, word := range strings.Fields(segment) {
+			prefix := strings.HasSuffix(word, "*")
+			parts := strings.FieldsFunc(word, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			for j, part := range parts {
+				lexeme := "'" + strings.ToLower(part) + "'"
+				if prefix && j == len(parts)-1 {
+					lexeme += ":*"
+				}
+				lexemes = append(lexemes, lexeme)
+			}
+		}
+		if len(lexemes) == 0 {
+			continue
+		}
+		// Odd segments sit between a pair of quotes.
+		if i%2 == 1 && len(lexemes) > 1 {
+			terms = append(terms, "("+strings.Join(lexemes, " <-> ")+")")
+		} else {
+			terms = append(terms, lexemes...)
+		}
+	}
+	if len(terms) == 0 {
+		return "", fmt.Errorf("Search query is empty")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// Search results are ordered by rank rather than (created_at, id), so their
+// cursor carries a result offset instead of a keyset position.
+func encodeOffsetCursor(offset int32) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(int(offset))))
+}
+
+func decodeOffsetCursor(cursor string) (int32, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 || offset > maxSearchOffset {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	return int32(offset), nil
+}
+
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	tsQuery, err := buildTSQuery(query.Get("q"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	limit, err := parseLimit(query)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	offset := int32(0)
+	if cursor := query.Get("cursor"); cursor != "" {
+		offset, err = decodeOffsetCursor(cursor)
+		if err != nil {
+			respondWithError(w, 400, "Invalid cursor")
+			return
+		}
+	}
+	authorID := uuid.NullUUID{}
+	if author := query.Get("author_id"); author != "" {
+		userID, err := uuid.Parse(author)
+		if err != nil {
+			respondWithError(w, 400, "Unable to retrieve Chirps")
+			return
+		}
+		authorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	viewer := cfg.optionalViewer(r)
+	dbChirps, err := cfg.database.SearchChirps(ctx, database.SearchChirpsParams{
+		Query:      tsQuery,
+		AuthorID:   authorID,
+		ViewerID:   viewer,
+		PageSize:   limit + 1,
+		PageOffset: offset,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 400, "Unable to search Chirps")
+		return
+	}
+	hasMore := len(dbChirps) > int(limit)
+	page, err := cfg.newChirpPage(ctx, dbChirps, limit, viewer)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to search Chirps")
+		return
+	}
+	page.NextCursor = ""
+	if hasMore && offset+limit <= maxSearchOffset {
+		page.NextCursor = encodeOffsetCursor(offset + limit)
+	}
+	respondWithJSON(w, 200, page)
+}
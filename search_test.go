@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       string
+		want    string
+		wantErr bool
+	}{
+		{name: "single word", q: "Chirpy", want: "'chirpy'"},
+		{name: "words are ANDed", q: "hello world", want: "'hello' & 'world'"},
+		{name: "prefix", q: "chirp*", want: "'chirp':*"},
+		{name: "phrase", q: `"hello big world"`, want: "('hello' <-> 'big' <-> 'world')"},
+		{name: "one word phrase", q: `"hello"`, want: "'hello'"},
+		{name: "phrase and words", q: `go "new release" now`, want: "'go' & ('new' <-> 'release') & 'now'"},
+		{name: "prefix inside phrase", q: `"open sour*"`, want: "('open' <-> 'sour':*)"},
+		{name: "punctuation splits words", q: "e-mail", want: "'e' & 'mail'"},
+		{name: "prefix applies to last part", q: "e-ma*", want: "'e' & 'ma':*"},
+		{name: "operators are stripped", q: "a & b | !c <-> (d)", want: "'a' & 'b' & 'c' & 'd'"},
+		{name: "quotes in words are stripped", q: "it's", want: "'it' & 's'"},
+		{name: "unicode letters kept", q: "Café naïve", want: "'café' & 'naïve'"},
+		{name: "unbalanced quote", q: `"open ended`, want: "('open' <-> 'ended')"},
+		{name: "empty", q: "", wantErr: true},
+		{name: "only punctuation", q: `!!! "" &|`, wantErr: true},
+		{name: "only a star", q: "*", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTSQuery(tt.q)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("buildTSQuery(%q) = %q, want error", tt.q, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildTSQuery(%q): %v", tt.q, err)
+			}
+			if got != tt.want {
+				t.Errorf("buildTSQuery(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOffsetCursor(t *testing.T) {
+	for _, offset := range []int32{0, 20, maxSearchOffset} {
+		got, err := decodeOffsetCursor(encodeOffsetCursor(offset))
+		if err != nil || got != offset {
+			t.Errorf("decodeOffsetCursor(encodeOffsetCursor(%d)) = (%d, %v)", offset, got, err)
+		}
+	}
+	for _, cursor := range []string{
+		"not base64!",
+		encodeOffsetCursor(-1),
+		encodeOffsetCursor(maxSearchOffset + 1),
+		encodeCursor(time.Now(), uuid.New()),
+	} {
+		if _, err := decodeOffsetCursor(cursor); err == nil {
+			t.Errorf("decodeOffsetCursor(%q) succeeded, want error", cursor)
+		}
+	}
+}
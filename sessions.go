@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+// Session is one signed-in device. Its ID is the refresh token family, which
+// stays the same while the refresh token itself is rotated.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+// parseTrustedProxies reads a comma-separated list of addresses and CIDR
+// ranges, such as "10.0.0.0/8,192.168.1.5", into prefixes.
+func parseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, err
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, err
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+func (cfg *apiConfig) trustedProxy(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range cfg.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the connection's peer. X-Forwarded-For is
+// only read when that peer is one of TRUSTED_PROXIES, since any client can
+// set it; the header is then walked from the right, past every trusted hop,
+// to the first address a trusted proxy saw connect.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !cfg.trustedProxy(host) {
+		return host
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		host = hop
+		if !cfg.trustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+func (cfg *apiConfig) getSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	rows, err := cfg.database.GetUserSessions(ctx, userID)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve sessions")
+		return
+	}
+	sessions := []Session{}
+	for _, row := range rows {
+		sessions = append(sessions, Session{
+			ID:         row.FamilyID,
+			SignedInAt: row.SignedInAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+		})
+	}
+	respondWithJSON(w, 200, sessions)
+}
+
+func (cfg *apiConfig) revokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid session ID")
+		return
+	}
+	revoked, err := cfg.database.RevokeUserSession(ctx, database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Unable to revoke session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Session not found")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	err = cfg.database.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to revoke sessions")
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,44 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens(id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    $5
+)
+RETURNING *;
+
+-- name: GetActiveAPIToken :one
+SELECT * FROM api_tokens
+WHERE token_hash = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW())
+    AND NOT EXISTS (
+        SELECT 1 FROM users
+        WHERE users.id = api_tokens.user_id AND users.suspended_until > NOW()
+    );
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1;
+
+-- name: GetUserAPITokens :many
+SELECT * FROM api_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: CountActiveAPITokens :one
+SELECT COUNT(*) FROM api_tokens
+WHERE user_id = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserAPITokens :exec
+UPDATE api_tokens SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
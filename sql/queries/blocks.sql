@@ -0,0 +1,51 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = sqlc.arg(user_a)::uuid AND blocked_id = sqlc.arg(user_b)::uuid)
+       OR (blocker_id = sqlc.arg(user_b)::uuid AND blocked_id = sqlc.arg(user_a)::uuid)
+);
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg(user_a)::uuid AND followee_id = sqlc.arg(user_b)::uuid)
+   OR (follower_id = sqlc.arg(user_b)::uuid AND followee_id = sqlc.arg(user_a)::uuid);
+
+-- name: GetBlockedUsers :many
+SELECT blocked_id, created_at FROM user_blocks
+WHERE blocker_id = sqlc.arg(user_id)
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, blocked_id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT muted_id, created_at FROM user_mutes
+WHERE muter_id = sqlc.arg(user_id)
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, muted_id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg(page_size);
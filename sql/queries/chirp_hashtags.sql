@@ -0,0 +1,43 @@
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT sqlc.arg(chirp_id)::uuid, unnest(sqlc.arg(tags)::text[]), NOW()
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags WHERE chirp_id = $1;
+
+-- name: GetHashtagChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg(tag)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetHashtagChirpsDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg(tag)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetTrendingHashtags :many
+SELECT chirp_hashtags.tag, COUNT(*) AS uses FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at >= sqlc.arg(since)::timestamp
+  AND chirps.deleted_at IS NULL
+  AND chirp_visible_to(chirps.user_id, NULL)
+GROUP BY chirp_hashtags.tag
+ORDER BY uses DESC, chirp_hashtags.tag ASC
+LIMIT sqlc.arg(max_tags);
@@ -0,0 +1,19 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: GetChirpLikeSummaries :many
+SELECT chirp_id,
+    COUNT(*) AS like_count,
+    COALESCE(BOOL_OR(user_id = sqlc.narg(viewer_id)::uuid), false)::bool AS liked_by_me
+FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id;
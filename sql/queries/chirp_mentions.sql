@@ -0,0 +1,56 @@
+-- name: ResolveUsernames :many
+SELECT id, LOWER(username)::text AS username FROM users
+WHERE LOWER(username) = ANY(sqlc.arg(usernames)::text[])
+  AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (user_blocks.blocker_id = users.id AND user_blocks.blocked_id = sqlc.arg(author_id)::uuid)
+       OR (user_blocks.blocker_id = sqlc.arg(author_id)::uuid AND user_blocks.blocked_id = users.id));
+
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset, created_at)
+SELECT sqlc.arg(chirp_id)::uuid,
+    unnest(sqlc.arg(user_ids)::uuid[]),
+    unnest(sqlc.arg(start_offsets)::int[]),
+    unnest(sqlc.arg(end_offsets)::int[]),
+    NOW();
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
+
+-- name: GetMentionsForChirps :many
+SELECT chirp_mentions.chirp_id, chirp_mentions.user_id, users.username,
+    chirp_mentions.start_offset, chirp_mentions.end_offset
+FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_mentions.chirp_id, chirp_mentions.start_offset;
+
+-- name: GetMentionChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+WHERE EXISTS (
+    SELECT 1 FROM chirp_mentions
+    WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg(user_id)
+)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.arg(user_id)::uuid)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetMentionChirpsDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+WHERE EXISTS (
+    SELECT 1 FROM chirp_mentions
+    WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg(user_id)
+)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.arg(user_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,17 @@
+-- name: CreateChirpRevision :one
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions WHERE chirp_id = $1 ORDER BY replaced_at ASC;
+
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions WHERE chirp_id = $1;
\ No newline at end of file
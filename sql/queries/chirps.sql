@@ -1,28 +1,157 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of;
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
     $2
 )
-RETURNING *;
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of;
+
+-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps WHERE user_id = $1 AND rechirp_of = $2;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps WHERE user_id = $1 AND rechirp_of = $2;
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps WHERE rechirp_of = $1;
+
+-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+  AND chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid);
 
 -- name: GetChirp :one
-SELECT * FROM chirps WHERE id = $1;
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps WHERE id = $1;
+
+-- name: GetChirpForViewer :one
+SELECT sqlc.embed(chirps),
+    chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid) AS visible
+FROM chirps
+WHERE chirps.id = sqlc.arg(id);
+
+-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $1, updated_at = NOW() WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of;
 
 -- name: GetChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetChirpsDesc :many
-SELECT * FROM chirps ORDER BY created_at DESC;
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, quote_of
+FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
--- name: GetUserChirps :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+WHERE chirps.search_vector @@ to_tsquery('english', sqlc.arg(query))
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id))
+  AND chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+ORDER BY ts_rank(chirps.search_vector, to_tsquery('english', sqlc.arg(query))) DESC,
+    chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
 
--- name: GetUserChirpsDesc :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at DESC;
+-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg(follower_id)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.arg(follower_id)::uuid)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetTimelineDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+    chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg(follower_id)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+  AND chirp_visible_to(chirps.user_id, sqlc.arg(follower_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1;
\ No newline at end of file
+DELETE FROM chirps WHERE id = $1;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW() WHERE id = $1;
+
+-- name: CountChirpDependents :one
+SELECT COUNT(*) FROM chirps
+WHERE in_reply_to = sqlc.arg(chirp_id)::uuid OR quote_of = sqlc.arg(chirp_id)::uuid;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, in_reply_to, depth) AS (
+    SELECT c.id, c.in_reply_to, 0 FROM chirps c WHERE c.id = sqlc.arg(chirp_id)::uuid
+    UNION ALL
+    SELECT p.id, p.in_reply_to, a.depth + 1
+    FROM chirps p JOIN ancestors a ON p.id = a.in_reply_to
+    WHERE a.depth < sqlc.arg(max_depth)::int
+)
+SELECT sqlc.embed(chirps),
+    chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid) AS visible
+FROM chirps
+WHERE chirps.id IN (SELECT ancestors.id FROM ancestors WHERE ancestors.depth > 0)
+ORDER BY chirps.created_at ASC, chirps.id ASC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants(id, depth) AS (
+    SELECT c.id, 1 FROM chirps c WHERE c.in_reply_to = sqlc.arg(chirp_id)::uuid
+    UNION ALL
+    SELECT r.id, d.depth + 1
+    FROM chirps r JOIN descendants d ON r.in_reply_to = d.id
+    WHERE d.depth < sqlc.arg(max_depth)::int
+)
+SELECT sqlc.embed(chirps),
+    chirp_visible_to(chirps.user_id, sqlc.narg(viewer_id)::uuid) AS visible
+FROM chirps
+WHERE chirps.id IN (SELECT descendants.id FROM descendants)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(max_chirps);
\ No newline at end of file
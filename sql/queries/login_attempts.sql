@@ -0,0 +1,25 @@
+-- name: AcquireLoginAttempt :one
+INSERT INTO login_attempts AS attempts (key, failures, last_failure_at)
+VALUES (sqlc.arg(key), 1, sqlc.arg(now)::timestamp)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN attempts.last_failure_at < sqlc.arg(window_start)::timestamp THEN 1
+        ELSE attempts.failures + 1
+    END,
+    last_failure_at = EXCLUDED.last_failure_at
+WHERE attempts.last_failure_at < sqlc.arg(window_start)::timestamp
+    OR attempts.failures <= sqlc.arg(free_attempts)::int
+    OR attempts.last_failure_at + LEAST(
+        sqlc.arg(max_delay_ms)::float8,
+        sqlc.arg(base_delay_ms)::float8 * power(2, attempts.failures - sqlc.arg(free_attempts)::int - 1)
+    ) * interval '1 millisecond' <= sqlc.arg(now)::timestamp
+RETURNING failures, last_failure_at;
+
+-- name: GetLoginAttempts :one
+SELECT * FROM login_attempts WHERE key = $1;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0;
+
+-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts WHERE key = $1;
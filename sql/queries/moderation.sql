@@ -0,0 +1,2 @@
+-- name: GetModerationWords :many
+SELECT word, action FROM moderation_words ORDER BY word;
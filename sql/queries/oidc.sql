@@ -0,0 +1,30 @@
+-- name: CreateOIDCState :exec
+INSERT INTO oidc_states(state_hash, code_verifier, nonce, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW(),
+    NOW() + INTERVAL '10 minutes'
+);
+
+-- name: ConsumeOIDCState :one
+DELETE FROM oidc_states
+WHERE state_hash = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOIDCStates :exec
+DELETE FROM oidc_states WHERE expires_at <= NOW();
+
+-- name: GetIdentityUser :one
+SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities(issuer, subject, user_id, email, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+);
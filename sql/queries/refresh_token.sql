@@ -1,16 +1,65 @@
 -- name: RegisterRefreshToken :one
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id, user_agent, ip_address, last_used_at)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    NOW() + INTERVAL '60 days'
+    NOW() + INTERVAL '60 days',
+    gen_random_uuid(),
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: RotateRefreshToken :one
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id, parent_token, user_agent, ip_address, last_used_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    NOW() + INTERVAL '60 days',
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
 )
 RETURNING *;
 
 -- name: LookUpRefreshToken :one
 SELECT * FROM refresh_tokens WHERE token = $1;
 
+-- name: ConsumeRefreshToken :execrows
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW();
+
 -- name: RevokeRefreshToken :exec
-UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE token = $1;
\ No newline at end of file
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE token = $1;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+
+-- name: GetUserSessions :many
+SELECT
+    family_id,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id)::timestamp AS signed_in_at,
+    last_used_at,
+    expires_at,
+    user_agent,
+    ip_address
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_used_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
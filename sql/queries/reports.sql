@@ -0,0 +1,73 @@
+-- name: CreateReport :one
+INSERT INTO reports(id, reporter_id, chirp_id, chirp_body, reported_user_id, reason, details, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: CreateFilterReport :exec
+INSERT INTO reports(id, chirp_id, chirp_body, reported_user_id, reason, details, created_at, updated_at)
+SELECT gen_random_uuid(), sqlc.arg(chirp_id)::uuid, sqlc.arg(chirp_body)::text, sqlc.arg(reported_user_id)::uuid, 'content_filter', sqlc.arg(details)::text, NOW(), NOW()
+WHERE NOT EXISTS (
+    SELECT 1 FROM reports
+    WHERE chirp_id = sqlc.arg(chirp_id)::uuid
+        AND reporter_id IS NULL
+        AND status IN ('open', 'claimed')
+);
+
+-- name: GetReport :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: GetReports :many
+SELECT * FROM reports
+WHERE (sqlc.narg(status)::text IS NULL AND status IN ('open', 'claimed') OR status = sqlc.narg(status))
+    AND (
+        sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (created_at, id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+    )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: ClaimReport :one
+UPDATE reports
+SET status = 'claimed', claimed_by = sqlc.arg(moderator_id), claimed_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+    AND (status = 'open' OR (status = 'claimed' AND claimed_by = sqlc.arg(moderator_id)))
+RETURNING *;
+
+-- name: CloseReport :one
+UPDATE reports
+SET status = sqlc.arg(status), claimed_by = COALESCE(claimed_by, sqlc.arg(moderator_id)), resolved_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+    AND (status = 'open' OR (status = 'claimed' AND claimed_by = sqlc.arg(moderator_id)))
+RETURNING *;
+
+-- name: CreateModerationDecision :one
+INSERT INTO moderation_decisions(id, report_id, moderator_id, action, note, suspended_until, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetReportDecisions :many
+SELECT * FROM moderation_decisions WHERE report_id = $1 ORDER BY created_at ASC;
+
+-- name: SuspendUser :execrows
+UPDATE users SET suspended_until = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: SetUserShadowBan :execrows
+UPDATE users SET shadow_banned = $1, updated_at = NOW() WHERE id = $2;
@@ -0,0 +1,28 @@
+-- name: SetPendingTOTPSecret :execrows
+UPDATE users SET totp_secret = $1, updated_at = NOW()
+WHERE id = $2 AND totp_enabled_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordTOTPStep :execrows
+UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2;
+
+-- name: DisableTOTP :exec
+UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (id, user_id, code_hash, created_at)
+SELECT gen_random_uuid(), sqlc.arg(user_id)::uuid, unnest(sqlc.arg(code_hashes)::text[]), NOW();
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
@@ -1,23 +1,65 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
 -- name: GetUserFromEmail :one
-SELECT id, created_at, updated_at, email, is_chirpy_red FROM users WHERE email = $1;
+SELECT id, created_at, updated_at, email, is_chirpy_red, username, email_verified_at, totp_enabled_at,
+    suspended_until FROM users WHERE email = $1;
+
+-- name: GetUser :one
+SELECT id, created_at, updated_at, email, is_chirpy_red, email_verified_at, username,
+    role, suspended_until, shadow_banned FROM users WHERE id = $1;
 
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
 
 -- name: UpdateUserEmailPassword :exec
 UPDATE users 
-SET email = COALESCE(NULLIF($1, email), email),
-    hashed_password = COALESCE(NULLIF($2, hashed_password), hashed_password)
+SET email = COALESCE(NULLIF($1, ''), email),
+    hashed_password = COALESCE(NULLIF($2, ''), hashed_password),
+    email_verified_at = CASE WHEN NULLIF($1, '') IS NULL OR $1 = email THEN email_verified_at END
 WHERE id = $3
-RETURNING id, email;
\ No newline at end of file
+RETURNING id, email;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET username = COALESCE(sqlc.narg(username), username),
+    display_name = COALESCE(sqlc.narg(display_name), display_name),
+    bio = COALESCE(sqlc.narg(bio), bio),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetUserProfile :one
+SELECT users.id, users.username, users.display_name, users.bio, users.created_at, users.is_chirpy_red,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.deleted_at IS NULL) AS chirp_count
+FROM users
+WHERE LOWER(users.username) = LOWER(sqlc.arg(username));
+
+
+-- name: MarkEmailVerified :execrows
+UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: SetUserRoleByEmail :execrows
+UPDATE users SET role = $1, updated_at = NOW() WHERE email = $2;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin';
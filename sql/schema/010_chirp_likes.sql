@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_likes(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_likes;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags(
+    chirp_id UUID NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag),
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+CREATE INDEX chirp_hashtags_tag_idx ON chirp_hashtags (tag);
+CREATE INDEX chirp_hashtags_created_at_idx ON chirp_hashtags (created_at);
+
+-- +goose Down
+DROP TABLE chirp_hashtags;
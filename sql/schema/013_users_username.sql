@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN username TEXT;
+CREATE UNIQUE INDEX users_username_lower_idx ON users (LOWER(username));
+
+-- +goose Down
+DROP INDEX users_username_lower_idx;
+ALTER TABLE users DROP COLUMN username;
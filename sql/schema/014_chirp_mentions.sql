@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE chirp_mentions(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    start_offset INTEGER NOT NULL,
+    end_offset INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, start_offset),
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
@@ -0,0 +1,64 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+
+CREATE TABLE reports(
+    id UUID PRIMARY KEY,
+    reporter_id UUID,
+    chirp_id UUID,
+    chirp_body TEXT,
+    reported_user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'claimed', 'resolved', 'dismissed')),
+    claimed_by UUID,
+    claimed_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (reported_user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (claimed_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX reports_queue_idx ON reports (status, created_at, id);
+CREATE UNIQUE INDEX reports_pending_chirp_idx ON reports (reporter_id, chirp_id)
+    WHERE status IN ('open', 'claimed') AND chirp_id IS NOT NULL;
+CREATE UNIQUE INDEX reports_pending_user_idx ON reports (reporter_id, reported_user_id)
+    WHERE status IN ('open', 'claimed') AND chirp_id IS NULL;
+
+CREATE TABLE moderation_decisions(
+    id UUID PRIMARY KEY,
+    report_id UUID NOT NULL,
+    moderator_id UUID,
+    action TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    suspended_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE CASCADE,
+    FOREIGN KEY (moderator_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX moderation_decisions_report_id_idx ON moderation_decisions (report_id);
+
+-- Content filter flags become reports without a reporter, so there is a
+-- single review queue.
+INSERT INTO reports(id, chirp_id, chirp_body, reported_user_id, reason, details, created_at, updated_at)
+SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.user_id, 'content_filter',
+    array_to_string(chirp_flags.words, ', '), chirp_flags.created_at, chirp_flags.created_at
+FROM chirp_flags
+JOIN chirps ON chirps.id = chirp_flags.chirp_id;
+DROP TABLE chirp_flags;
+
+-- +goose Down
+CREATE TABLE chirp_flags(
+    chirp_id UUID PRIMARY KEY,
+    words TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+INSERT INTO chirp_flags(chirp_id, words, created_at)
+SELECT DISTINCT ON (chirp_id) chirp_id, string_to_array(details, ', '), created_at
+FROM reports
+WHERE reason = 'content_filter' AND status IN ('open', 'claimed') AND chirp_id IN (SELECT id FROM chirps)
+ORDER BY chirp_id, created_at DESC;
+DROP TABLE moderation_decisions;
+DROP TABLE reports;
+ALTER TABLE users DROP COLUMN suspended_until;
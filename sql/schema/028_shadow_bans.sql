@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN shadow_banned BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN shadow_banned;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE user_blocks(
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (blocker_id <> blocked_id)
+);
+CREATE INDEX user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+CREATE INDEX user_blocks_blocker_id_created_at_idx ON user_blocks (blocker_id, created_at);
+
+CREATE TABLE user_mutes(
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (muter_id <> muted_id)
+);
+CREATE INDEX user_mutes_muter_id_created_at_idx ON user_mutes (muter_id, created_at);
+
+-- +goose Down
+DROP TABLE user_mutes;
+DROP TABLE user_blocks;
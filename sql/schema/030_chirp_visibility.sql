@@ -0,0 +1,21 @@
+-- +goose Up
+-- chirp_visible_to decides whether a chirp by chirp_user may be listed for
+-- viewer, who is NULL for anonymous requests: shadow-banned authors are
+-- hidden from everyone but themselves, blocks hide chirps both ways and
+-- mutes hide the muted user from the muter.
+-- +goose StatementBegin
+CREATE FUNCTION chirp_visible_to(chirp_user UUID, viewer UUID) RETURNS boolean AS $$
+    SELECT (COALESCE(chirp_user = viewer, false) OR NOT EXISTS (
+            SELECT 1 FROM users WHERE users.id = chirp_user AND users.shadow_banned))
+        AND NOT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE (user_blocks.blocker_id = viewer AND user_blocks.blocked_id = chirp_user)
+               OR (user_blocks.blocker_id = chirp_user AND user_blocks.blocked_id = viewer))
+        AND NOT EXISTS (
+            SELECT 1 FROM user_mutes
+            WHERE user_mutes.muter_id = viewer AND user_mutes.muted_id = chirp_user)
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_visible_to(UUID, UUID);
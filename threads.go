@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxThreadDepth   = 200
+	maxThreadReplies = 1000
+)
+
+type ThreadNode struct {
+	Chirp
+	Replies []*ThreadNode `json:"replies"`
+}
+
+type ChirpThread struct {
+	Ancestors []Chirp     `json:"ancestors"`
+	Chirp     *ThreadNode `json:"chirp"`
+}
+
+// tombstoneChirp blanks a chirp that still has replies instead of deleting
+// it, so the conversation below it keeps its shape. q should be bound to a
+// transaction so the chirp is never left half cleared.
+func tombstoneChirp(ctx context.Context, q *database.Queries, id uuid.UUID) error {
+	err := q.TombstoneChirp(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteChirpRevisions(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteChirpHashtags(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteChirpMentions(ctx, id)
+	if err != nil {
+		return err
+	}
+	return q.DeleteRechirpsOf(ctx, uuid.NullUUID{UUID: id, Valid: true})
+}
+
+// threadChirp renders a chirp in a thread, or a placeholder when it is hidden
+// from the viewer so the replies below it keep their place.
+func threadChirp(dbChirp database.Chirp, visible bool) Chirp {
+	if !visible {
+		return unavailableChirp(dbChirp)
+	}
+	return chirpFromDB(dbChirp)
+}
+
+func (cfg *apiConfig) getChirpThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Unable to parse request")
+		return
+	}
+	viewer := cfg.optionalViewer(r)
+	root, err := cfg.database.GetChirpForViewer(ctx, database.GetChirpForViewerParams{
+		ViewerID: viewer,
+		ID:       id,
+	})
+	if err != nil {
+		respondWithError(w, 404, "")
+		return
+	}
+	dbAncestors, err := cfg.database.GetChirpAncestors(ctx, database.GetChirpAncestorsParams{
+		ChirpID:  id,
+		MaxDepth: maxThreadDepth,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve thread")
+		return
+	}
+	dbDescendants, err := cfg.database.GetChirpDescendants(ctx, database.GetChirpDescendantsParams{
+		ChirpID:   id,
+		MaxDepth:  maxThreadDepth,
+		ViewerID:  viewer,
+		MaxChirps: maxThreadReplies,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to retrieve thread")
+		return
+	}
+	thread := ChirpThread{
+		Ancestors: []Chirp{},
+		Chirp:     &ThreadNode{Chirp: threadChirp(root.Chirp, root.Visible), Replies: []*ThreadNode{}},
+	}
+	for _, dbAncestor := range dbAncestors {
+		thread.Ancestors = append(thread.Ancestors, threadChirp(dbAncestor.Chirp, dbAncestor.Visible))
+	}
+	// Descendants arrive oldest first, and a reply is always newer than the
+	// chirp it answers, so every parent is in the map before its children.
+	nodes := map[uuid.UUID]*ThreadNode{id: thread.Chirp}
+	for _, dbDescendant := range dbDescendants {
+		parent, ok := nodes[dbDescendant.Chirp.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		node := &ThreadNode{Chirp: threadChirp(dbDescendant.Chirp, dbDescendant.Visible), Replies: []*ThreadNode{}}
+		parent.Replies = append(parent.Replies, node)
+		nodes[node.ID] = node
+	}
+	chirps := []*Chirp{&thread.Chirp.Chirp}
+	for i := range thread.Ancestors {
+		chirps = append(chirps, &thread.Ancestors[i])
+	}
+	for nodeID, node := range nodes {
+		if nodeID != id {
+			chirps = append(chirps, &node.Chirp)
+		}
+	}
+	err = cfg.decorateChirps(ctx, chirps, viewer)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve thread")
+		return
+	}
+	respondWithJSON(w, 200, thread)
+}
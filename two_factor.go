@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	totpIssuer            = "Chirpy"
+	recoveryCodeCount     = 10
+)
+
+type twoFactorCode struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// twoFactorThrottleKey is the lockout key for a user's second-factor codes,
+// shared by every endpoint that accepts one.
+func twoFactorThrottleKey(userID uuid.UUID) string {
+	return "two-factor:" + userID.String()
+}
+
+func (cfg *apiConfig) respondWithTwoFactorChallenge(w http.ResponseWriter, userID uuid.UUID) {
+	type challengeResponse struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+	}
+	token, err := auth.MakePurposeJWT(userID, auth.PurposeTwoFactorChallenge, "", cfg.purposeSecret, twoFactorChallengeTTL)
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
+	respondWithJSON(w, 200, challengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+	})
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. A TOTP time step is only accepted once, so an intercepted code
+// cannot be replayed.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, userID uuid.UUID, secret string, factor twoFactorCode) bool {
+	if factor.RecoveryCode != "" {
+		used, err := cfg.database.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(factor.RecoveryCode),
+		})
+		return err == nil && used == 1
+	}
+	step, ok := auth.ValidateTOTP(secret, factor.Code, time.Now())
+	if !ok {
+		return false
+	}
+	recorded, err := cfg.database.RecordTOTPStep(ctx, database.RecordTOTPStepParams{
+		ID:           userID,
+		TotpLastStep: step,
+	})
+	return err == nil && recorded == 1
+}
+
+func (cfg *apiConfig) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type twoFactorLogin struct {
+		ChallengeToken string `json:"challenge_token"`
+		twoFactorCode
+	}
+	ctx := r.Context()
+	request := twoFactorLogin{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	userID, _, err := auth.ValidatePurposeJWT(request.ChallengeToken, auth.PurposeTwoFactorChallenge, cfg.purposeSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid or expired challenge")
+		return
+	}
+	totp, err := cfg.database.GetUserTOTP(ctx, userID)
+	if err != nil || !totp.TotpEnabledAt.Valid {
+		respondWithError(w, 401, "Invalid or expired challenge")
+		return
+	}
+	// Six digits are guessable without a limit, so codes are throttled per
+	// user on top of the password attempts.
+	attempt, wait := cfg.beginLoginAttempt(r, twoFactorThrottleKey(userID))
+	if wait > 0 {
+		respondWithTooManyAttempts(w, wait)
+		return
+	}
+	if !cfg.checkSecondFactor(ctx, userID, totp.TotpSecret.String, request.twoFactorCode) {
+		cfg.loginFailed(r, attempt, "")
+		respondWithError(w, 401, "Incorrect code")
+		return
+	}
+	cfg.loginSucceeded(ctx, attempt)
+	dbUser, err := cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to retrieve user details")
+		return
+	}
+	if isSuspended(dbUser.SuspendedUntil) {
+		respondWithSuspended(w, dbUser.SuspendedUntil)
+		return
+	}
+	user := User{
+		ID:        dbUser.ID,
+		CreatedAt: dbUser.CreatedAt,
+		UpdatedAt: dbUser.UpdatedAt,
+		Email:     dbUser.Email,
+		Username:  dbUser.Username.String,
+		Verified:  dbUser.EmailVerifiedAt.Valid,
+		ChirpyRed: dbUser.IsChirpyRed.Bool,
+	}
+	err = cfg.startSession(r, &user)
+	if err != nil {
+		respondWithError(w, 500, "Unable to create session")
+		return
+	}
+	respondWithJSON(w, 200, user)
+}
+
+func (cfg *apiConfig) setupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type setupResponse struct {
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	dbUser, err := cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, 500, "Unable to set up two-factor authentication")
+		return
+	}
+	updated, err := cfg.database.SetPendingTOTPSecret(ctx, database.SetPendingTOTPSecretParams{
+		TotpSecret: nullString(&secret),
+		ID:         userID,
+	})
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to set up two-factor authentication")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	respondWithJSON(w, 200, setupResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, dbUser.Email, totpIssuer),
+	})
+}
+
+func (cfg *apiConfig) enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type enableResponse struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	request := twoFactorCode{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	totp, err := cfg.database.GetUserTOTP(ctx, userID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	if totp.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if !totp.TotpSecret.Valid {
+		respondWithError(w, 400, "Two-factor setup has not been started")
+		return
+	}
+	step, ok := auth.ValidateTOTP(totp.TotpSecret.String, request.Code, time.Now())
+	if !ok {
+		respondWithError(w, 401, "Incorrect code")
+		return
+	}
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, 500, "Unable to enable two-factor authentication")
+		return
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashRecoveryCode(code))
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to enable two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	err = qtx.EnableTOTP(ctx, database.EnableTOTPParams{ID: userID, TotpLastStep: step})
+	if err != nil {
+		respondWithError(w, 500, "Unable to enable two-factor authentication")
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to enable two-factor authentication")
+		return
+	}
+	err = qtx.CreateRecoveryCodes(ctx, database.CreateRecoveryCodesParams{UserID: userID, CodeHashes: hashes})
+	if err != nil {
+		respondWithError(w, 500, "Unable to enable two-factor authentication")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to enable two-factor authentication")
+		return
+	}
+	respondWithJSON(w, 200, enableResponse{RecoveryCodes: codes})
+}
+
+func (cfg *apiConfig) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	request := twoFactorCode{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	totp, err := cfg.database.GetUserTOTP(ctx, userID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	if !totp.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is not enabled")
+		return
+	}
+	// A stolen access token must not be enough to brute-force the code and
+	// strip 2FA, so this shares the login challenge's attempt budget.
+	attempt, wait := cfg.beginLoginAttempt(r, twoFactorThrottleKey(userID))
+	if wait > 0 {
+		respondWithTooManyAttempts(w, wait)
+		return
+	}
+	if !cfg.checkSecondFactor(ctx, userID, totp.TotpSecret.String, request) {
+		cfg.loginFailed(r, attempt, "")
+		respondWithError(w, 401, "Incorrect code")
+		return
+	}
+	cfg.loginSucceeded(ctx, attempt)
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, 500, "Unable to disable two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.database.WithTx(tx)
+	err = qtx.DisableTOTP(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to disable two-factor authentication")
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Unable to disable two-factor authentication")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Unable to disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/auth"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/database"
+	"github.com/TheodoreRoosevelt26/Chirpy-project.git/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+var (
+	errEmailNotVerified   = errors.New("Email address must be verified before posting")
+	errInvalidVerifyToken = errors.New("Invalid or expired verification token")
+)
+
+// validateEmail accepts a bare address such as "user@example.com" and
+// rejects display-name forms and anything mail.ParseAddress cannot read.
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return fmt.Errorf("Invalid email address")
+	}
+	return nil
+}
+
+// sendVerificationEmail mails a signed link for the user's current address.
+// The token is bound to that address, so it stops working if the email
+// changes before it is used.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := auth.MakePurposeJWT(userID, auth.PurposeEmailVerification, email, cfg.purposeSecret, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/api/users/verify?token=%s", cfg.baseURL, url.QueryEscape(token))
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Welcome to Chirpy!\n\nConfirm your email address by opening %s\n"+
+			"or by sending this token to POST /api/users/verify:\n\n%s\n\nThe link expires in %v.\n",
+			link, token, emailVerificationTTL),
+	})
+}
+
+// ensureEmailVerified enforces REQUIRE_VERIFIED_EMAIL for endpoints that
+// publish chirps.
+func (cfg *apiConfig) ensureEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	if !cfg.requireVerified {
+		return nil
+	}
+	dbUser, err := cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !dbUser.EmailVerifiedAt.Valid {
+		return errEmailNotVerified
+	}
+	return nil
+}
+
+// confirmEmail marks the address a verification token was minted for as
+// verified. Reusing a token for the same address is not an error.
+func (cfg *apiConfig) confirmEmail(ctx context.Context, token string) error {
+	userID, email, err := auth.ValidatePurposeJWT(token, auth.PurposeEmailVerification, cfg.purposeSecret)
+	if err != nil {
+		return errInvalidVerifyToken
+	}
+	updated, err := cfg.database.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{
+		ID:    userID,
+		Email: email,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		dbUser, err := cfg.database.GetUser(ctx, userID)
+		if err != nil || dbUser.Email != email {
+			return errInvalidVerifyToken
+		}
+	}
+	return nil
+}
+
+func respondWithVerifyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidVerifyToken) {
+		respondWithError(w, 401, err.Error())
+		return
+	}
+	fmt.Printf("Error %v", err)
+	respondWithError(w, 500, "Unable to verify email")
+}
+
+func (cfg *apiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	type verificationRequest struct {
+		Token string `json:"token"`
+	}
+	request := verificationRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 400, "Unable to process request")
+		return
+	}
+	err = cfg.confirmEmail(r.Context(), request.Token)
+	if err != nil {
+		respondWithVerifyError(w, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// verifyEmailLink handles the link in the verification email, which is
+// opened in a browser rather than called by a client.
+func (cfg *apiConfig) verifyEmailLink(w http.ResponseWriter, r *http.Request) {
+	err := cfg.confirmEmail(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		respondWithVerifyError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(200)
+	w.Write([]byte("Your email address is verified. You can close this page.\n"))
+}
+
+func (cfg *apiConfig) resendVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	dbUser, err := cfg.database.GetUser(ctx, userID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	if dbUser.EmailVerifiedAt.Valid {
+		respondWithError(w, 409, "Email is already verified")
+		return
+	}
+	err = cfg.sendVerificationEmail(ctx, dbUser.ID, dbUser.Email)
+	if err != nil {
+		fmt.Printf("Error %v", err)
+		respondWithError(w, 500, "Unable to send verification email")
+		return
+	}
+	w.WriteHeader(204)
+}